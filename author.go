@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/github"
+	log "github.com/sirupsen/logrus"
+)
+
+// Author the person a post is attributed to
+type Author struct {
+	Login     string `json:"login,omitempty" yaml:"login,omitempty"`
+	Name      string `json:"name,omitempty" yaml:"name,omitempty"`
+	URL       string `json:"url,omitempty" yaml:"url,omitempty"`
+	AvatarURL string `json:"avatarURL,omitempty" yaml:"avatarURL,omitempty"`
+	Bio       string `json:"bio,omitempty" yaml:"bio,omitempty"`
+}
+
+// authorsByOwner profiles already fetched this run, without the authors config which can change with the profile
+var authorsByOwner = make(map[string]Author)
+var authorsByOwnerMutex sync.Mutex
+
+func getRepoOwnerLogin(repo *github.Repository) string {
+	if login := repo.GetOwner().GetLogin(); login != "" {
+		return login
+	}
+	return strings.Split(repo.GetFullName(), "/")[0]
+}
+
+func getCachedUserPath(login string) string {
+	return filepath.Join(tempDirectoryName, "user-"+login+".json")
+}
+
+// getGithubUser the github profile of login, cached under tmp like the repo list
+func getGithubUser(login string, cache bool) (*github.User, error) {
+	cachedUserPath := getCachedUserPath(login)
+	if cache && fileExists(cachedUserPath) {
+		blob, _ := ioutil.ReadFile(cachedUserPath)
+		var githubUser github.User
+		if err := json.Unmarshal(blob, &githubUser); err != nil {
+			log.Printf("failed to unmarshall user %s\n", login)
+			return nil, err
+		}
+		return &githubUser, nil
+	}
+
+	githubUser, _, err := getGithubClient().Users.Get(context.Background(), login)
+	if err != nil {
+		log.Printf("failed to get user %s\n", login)
+		return nil, err
+	}
+
+	if cache {
+		os.MkdirAll(tempDirectoryName, os.ModePerm)
+		blob, err := json.Marshal(githubUser)
+		if err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(cachedUserPath, blob, 0644); err != nil {
+			log.Printf("ioutil.WriteFile(%s) failed\n", cachedUserPath)
+			return nil, err
+		}
+	}
+	return githubUser, nil
+}
+
+func overrideAuthor(author Author, override Author) Author {
+	if override.Name != "" {
+		author.Name = override.Name
+	}
+	if override.URL != "" {
+		author.URL = override.URL
+	}
+	if override.AvatarURL != "" {
+		author.AvatarURL = override.AvatarURL
+	}
+	if override.Bio != "" {
+		author.Bio = override.Bio
+	}
+	return author
+}
+
+// getGithubAuthor the author from the owner's github profile, fetched once a run.
+// when the profile can't be fetched the owner's login is used as the name
+func getGithubAuthor(login string) Author {
+	authorsByOwnerMutex.Lock()
+	defer authorsByOwnerMutex.Unlock()
+	if author, ok := authorsByOwner[login]; ok {
+		return author
+	}
+
+	author := Author{Login: login, Name: login, URL: "https://github.com/" + login}
+	if githubUser, err := getGithubUser(login, useCache); err != nil {
+		log.Warnf("getGithubUser(%s) failed: %v\n", login, err)
+	} else {
+		if githubUser.GetName() != "" {
+			author.Name = githubUser.GetName()
+		}
+		if githubUser.GetHTMLURL() != "" {
+			author.URL = githubUser.GetHTMLURL()
+		}
+		author.AvatarURL = githubUser.GetAvatarURL()
+		author.Bio = githubUser.GetBio()
+	}
+	authorsByOwner[login] = author
+	return author
+}
+
+// getPostAuthor the author of a repo's post from the owner's github profile and the authors config
+func getPostAuthor(repo *github.Repository) Author {
+	login := getRepoOwnerLogin(repo)
+	return overrideAuthor(getGithubAuthor(login), getConfig().Authors[login])
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func TestGetPostAuthor(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/users/octocat":
+			w.Write([]byte(`{"login": "octocat", "name": "The Octocat", "html_url": "https://github.com/octocat", "avatar_url": "https://avatars.example.com/octocat", "bio": "cat"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	os.Setenv("GITHUB_API_BASE_URL", server.URL)
+	defer os.Unsetenv("GITHUB_API_BASE_URL")
+	os.Setenv("AUTHOR_OVERRIDES_JSON", `{"pfeilbr": {"name": "Brian Pfeil", "url": "https://example.com"}}`)
+	defer os.Unsetenv("AUTHOR_OVERRIDES_JSON")
+	defer func(cache bool) { useCache = cache }(useCache)
+	useCache = false
+	authorsByOwner = make(map[string]Author)
+
+	login := "octocat"
+	repo := &github.Repository{FullName: github.String("octocat/hello-world"), Owner: &github.User{Login: &login}}
+	author := getPostAuthor(repo)
+	want := Author{Login: "octocat", Name: "The Octocat", URL: "https://github.com/octocat", AvatarURL: "https://avatars.example.com/octocat", Bio: "cat"}
+	if author != want {
+		t.Errorf("expected %+v, got %+v", want, author)
+	}
+	getPostAuthor(repo)
+	if requests != 1 {
+		t.Errorf("expected the profile to be fetched once, got %d requests", requests)
+	}
+
+	// the profile of pfeilbr 404s so the login and overrides are used
+	author = getPostAuthor(newTestRepo("lambda-playground"))
+	if author.Name != "Brian Pfeil" || author.URL != "https://example.com" || author.Login != "pfeilbr" {
+		t.Errorf("unexpected author %+v", author)
+	}
+
+	// the overrides of another profile apply to the cached profile
+	os.Setenv("AUTHOR_OVERRIDES_JSON", `{"octocat": {"name": "Mona"}}`)
+	if author := getPostAuthor(repo); author.Name != "Mona" || author.Bio != "cat" || requests != 2 {
+		t.Errorf("expected the new override on the cached profile, got %+v after %d requests", author, requests)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const cleanupRuleBadges = "badges"
+const cleanupRuleBoilerplate = "boilerplate"
+const cleanupRulePattern = "pattern"
+
+// CleanupOptions which noise is removed from a post body
+type CleanupOptions struct {
+	StripBadges             bool
+	BoilerplateFingerprints map[string]bool
+	Patterns                []*regexp.Regexp
+}
+
+// CleanupRemoval a block of markdown removed from a post body and the rule that removed it
+type CleanupRemoval struct {
+	Rule string
+	Text string
+}
+
+func (removal CleanupRemoval) String() string {
+	text := strings.Join(strings.Fields(removal.Text), " ")
+	if len(text) > 80 {
+		text = text[:77] + "..."
+	}
+	return fmt.Sprintf("%s: %q", removal.Rule, text)
+}
+
+// known boilerplate paragraphs. matched by fingerprint so whitespace and case differences don't matter.
+var knownBoilerplateParagraphs = []string{
+	"This project was bootstrapped with [Create React App](https://github.com/facebook/create-react-app).",
+	"This project was bootstrapped with [Create React App](https://github.com/facebookincubator/create-react-app).",
+	"In the project directory, you can run:",
+	"Runs the app in the development mode.<br />\nOpen [http://localhost:3000](http://localhost:3000) to view it in the browser.",
+	"Runs the app in the development mode.\\\nOpen [http://localhost:3000](http://localhost:3000) to view it in your browser.",
+	"The page will reload if you make edits.<br />\nYou will also see any lint errors in the console.",
+	"Launches the test runner in the interactive watch mode.<br />\nSee the section about [running tests](https://facebook.github.io/create-react-app/docs/running-tests) for more information.",
+	"Builds the app for production to the `build` folder.<br />\nIt correctly bundles React in production mode and optimizes the build for the best performance.",
+	"The build is minified and the filenames include the hashes.<br />\nYour app is ready to be deployed!",
+	"See the section about [deployment](https://facebook.github.io/create-react-app/docs/deployment) for more information.",
+	"**Note: this is a one-way operation. Once you `eject`, you can’t go back!**",
+	"You can learn more in the [Create React App documentation](https://facebook.github.io/create-react-app/docs/getting-started).",
+	"To learn React, check out the [React documentation](https://reactjs.org/).",
+	"This is a [Next.js](https://nextjs.org/) project bootstrapped with [`create-next-app`](https://github.com/vercel/next.js/tree/canary/packages/create-next-app).",
+	"This project was generated with [Angular CLI](https://github.com/angular/angular-cli).",
+	"This project was generated using cookiecutter.",
+}
+
+var badgeImageRegexp = regexp.MustCompile(`^!\[[^\]]*\]\(([^)\s]+)[^)]*\)$`)
+var linkedBadgeRegexp = regexp.MustCompile(`^\[(!\[[^\]]*\]\([^)]*\))\]\([^)]*\)$`)
+var htmlBadgeImageRegexp = regexp.MustCompile(`<img[^>]*src=["']([^"']+)["'][^>]*>`)
+var badgeTokenRegexp = regexp.MustCompile(`\[!\[[^\]]*\]\([^)]*\)\]\([^)]*\)|!\[[^\]]*\]\([^)]*\)|<a[^>]*>\s*<img[^>]*>\s*</a>|<img[^>]*>|</?p[^>]*>`)
+var badgeURLRegexp = regexp.MustCompile(`(?i)(shields\.io|badgen\.net|badge\.fury\.io|travis-ci\.(org|com)|circleci\.com|codecov\.io|coveralls\.io|ci\.appveyor\.com|github\.com/.+/(workflows|actions)/.+badge\.svg|/badges?[/.]|badge\.svg|\.svg\?branch=)`)
+
+// getMarkdownFingerprint a hash of a markdown block that ignores case and whitespace
+func getMarkdownFingerprint(markdown string) string {
+	normalized := strings.ToLower(strings.Join(strings.Fields(markdown), " "))
+	hash := sha1.Sum([]byte(normalized))
+	return hex.EncodeToString(hash[:])
+}
+
+func getKnownBoilerplateFingerprints() map[string]bool {
+	fingerprints := make(map[string]bool)
+	for _, paragraph := range knownBoilerplateParagraphs {
+		fingerprints[getMarkdownFingerprint(paragraph)] = true
+	}
+	return fingerprints
+}
+
+func isBadgeImage(image string) bool {
+	if match := linkedBadgeRegexp.FindStringSubmatch(image); match != nil {
+		image = match[1]
+	}
+	if match := badgeImageRegexp.FindStringSubmatch(image); match != nil {
+		return badgeURLRegexp.MatchString(match[1])
+	}
+	if match := htmlBadgeImageRegexp.FindStringSubmatch(image); match != nil {
+		return badgeURLRegexp.MatchString(match[1])
+	}
+	return false
+}
+
+// isBadgeParagraph true when a paragraph is nothing but badge images, optionally linked
+func isBadgeParagraph(paragraph string) bool {
+	tokens := badgeTokenRegexp.FindAllString(paragraph, -1)
+	if len(tokens) == 0 || strings.TrimSpace(badgeTokenRegexp.ReplaceAllString(paragraph, "")) != "" {
+		return false
+	}
+
+	images := 0
+	for _, token := range tokens {
+		if strings.HasPrefix(token, "<p") || strings.HasPrefix(token, "</p") {
+			continue
+		}
+		if !isBadgeImage(token) {
+			return false
+		}
+		images++
+	}
+	return images > 0
+}
+
+// cleanupMarkdown removes badge-only paragraphs, known boilerplate and pattern matches from markdown outside of code blocks
+func cleanupMarkdown(markdown string, options CleanupOptions) (string, []CleanupRemoval) {
+	removals := make([]CleanupRemoval, 0)
+
+	cleaned := mapMarkdownText(markdown, func(text string) string {
+		for _, pattern := range options.Patterns {
+			text = pattern.ReplaceAllStringFunc(text, func(match string) string {
+				removals = append(removals, CleanupRemoval{Rule: cleanupRulePattern + " " + pattern.String(), Text: match})
+				return ""
+			})
+		}
+
+		paragraphs := strings.Split(text, "\n\n")
+		kept := make([]string, 0, len(paragraphs))
+		for i, paragraph := range paragraphs {
+			trimmed := strings.TrimSpace(paragraph)
+			if trimmed == "" {
+				kept = append(kept, paragraph)
+				continue
+			}
+
+			rule := ""
+			if options.StripBadges && isBadgeParagraph(trimmed) {
+				rule = cleanupRuleBadges
+			} else if options.BoilerplateFingerprints[getMarkdownFingerprint(trimmed)] {
+				rule = cleanupRuleBoilerplate
+			}
+			if rule == "" {
+				kept = append(kept, paragraph)
+				continue
+			}
+
+			removals = append(removals, CleanupRemoval{Rule: rule, Text: trimmed})
+			if i == len(paragraphs)-1 {
+				kept = append(kept, paragraph[len(strings.TrimRight(paragraph, "\n")):])
+			}
+		}
+		return strings.Join(kept, "\n\n")
+	})
+
+	return cleaned, removals
+}
+
+func getCleanupOptions() (CleanupOptions, error) {
+	config := getConfig()
+	options := CleanupOptions{
+		StripBadges:             containsTrimmed(config.Body.CleanupRules, cleanupRuleBadges),
+		BoilerplateFingerprints: make(map[string]bool),
+		Patterns:                make([]*regexp.Regexp, 0),
+	}
+
+	if containsTrimmed(config.Body.CleanupRules, cleanupRuleBoilerplate) {
+		options.BoilerplateFingerprints = getKnownBoilerplateFingerprints()
+		for _, fingerprint := range config.Body.BoilerplateFingerprints {
+			options.BoilerplateFingerprints[strings.TrimSpace(fingerprint)] = true
+		}
+	}
+
+	for _, pattern := range config.Body.StripPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Printf("regexp.Compile(%s) failed\n", pattern)
+			return options, err
+		}
+		options.Patterns = append(options.Patterns, re)
+	}
+	return options, nil
+}
+
+func containsTrimmed(items []string, value string) bool {
+	for _, item := range items {
+		if strings.TrimSpace(item) == value {
+			return true
+		}
+	}
+	return false
+}
+
+func cleanupPostBody(repoName string, markdown string) (string, []CleanupRemoval, error) {
+	options, err := getCleanupOptions()
+	if err != nil {
+		log.Printf("getCleanupOptions() failed\n")
+		return "", nil, err
+	}
+
+	cleaned, removals := cleanupMarkdown(markdown, options)
+	for _, removal := range removals {
+		log.Printf("cleanupPostBody(%s) removed %s\n", repoName, removal)
+	}
+	return cleaned, removals, nil
+}
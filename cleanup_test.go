@@ -0,0 +1,52 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestCleanupMarkdown(t *testing.T) {
+	options := CleanupOptions{
+		StripBadges:             true,
+		BoilerplateFingerprints: getKnownBoilerplateFingerprints(),
+		Patterns:                []*regexp.Regexp{regexp.MustCompile(`(?m)^Made with .* by .*\n?`)},
+	}
+
+	markdown := "\n[![Build Status](https://travis-ci.org/pfeilbr/x.svg?branch=master)](https://travis-ci.org/pfeilbr/x) ![npm](https://img.shields.io/npm/v/x.svg)\n\n" +
+		"This project was bootstrapped with [Create React App](https://github.com/facebook/create-react-app).\n\n" +
+		"learn react ![diagram](images/diagram.png)\n\n" +
+		"<p align=\"center\">\n  <a href=\"https://circleci.com/gh/x\"><img src=\"https://circleci.com/gh/x.svg?style=svg\"></a>\n</p>\n\n" +
+		"```md\n![npm](https://img.shields.io/npm/v/x.svg)\n```\n\n" +
+		"Made with love by pfeilbr\n"
+
+	want := "learn react ![diagram](images/diagram.png)\n\n" +
+		"```md\n![npm](https://img.shields.io/npm/v/x.svg)\n```\n\n"
+
+	result, removals := cleanupMarkdown(markdown, options)
+	if result != want {
+		t.Errorf("got %q, want %q", result, want)
+	}
+
+	rules := make([]string, 0)
+	for _, removal := range removals {
+		rules = append(rules, removal.Rule)
+	}
+	wantRules := []string{cleanupRuleBadges, cleanupRuleBoilerplate, cleanupRuleBadges, "pattern (?m)^Made with .* by .*\\n?"}
+	if len(rules) != len(wantRules) {
+		t.Fatalf("got %v, want %v", rules, wantRules)
+	}
+	for i := range wantRules {
+		if rules[i] != wantRules[i] {
+			t.Errorf("got %v, want %v", rules, wantRules)
+		}
+	}
+}
+
+func TestIsBadgeParagraph(t *testing.T) {
+	if isBadgeParagraph("![screenshot](https://example.com/screenshot.png)") {
+		t.Errorf("screenshot should not be a badge")
+	}
+	if !isBadgeParagraph("![](https://github.com/pfeilbr/x/workflows/CI/badge.svg)") {
+		t.Errorf("github actions badge not detected")
+	}
+}
@@ -0,0 +1,614 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultConfigFileName = "config.yaml"
+
+// Config everything that decides which repos become posts and what the posts look like. see config.sample.yaml for the schema.
+// keys are read from the config file, then from the .env variable named by the env tag, then from -set flags
+type Config struct {
+	GitHub    GitHubConfig      `yaml:"github"`
+	Filters   FiltersConfig     `yaml:"filters"`
+	Titles    TitlesConfig      `yaml:"titles"`
+	Summaries SummariesConfig   `yaml:"summaries"`
+	Tags      TagsConfig        `yaml:"tags"`
+	Authors   map[string]Author `yaml:"authors" env:"AUTHOR_OVERRIDES_JSON"`
+	Body      BodyConfig        `yaml:"body"`
+	Templates TemplatesConfig   `yaml:"templates"`
+	Outputs   OutputsConfig     `yaml:"outputs"`
+	LinkCheck LinkCheckConfig   `yaml:"linkCheck"`
+	// named sets of keys laid over the keys above by -profile, e.g. one per site posts are generated for
+	Profiles map[string]Config `yaml:"profiles"`
+}
+
+// GitHubConfig whose repos are read and how
+type GitHubConfig struct {
+	User        string `yaml:"user" env:"GITHUB_USERNAME"`
+	AccessToken string `yaml:"accessToken" env:"GITHUB_ACCESS_TOKEN"`
+	APIBaseURL  string `yaml:"apiBaseURL" env:"GITHUB_API_BASE_URL"`
+}
+
+// FiltersConfig regular expressions a repo name has to match, and not match, to become a post
+type FiltersConfig struct {
+	Include []string `yaml:"include" env:"REPO_NAME_INCLUDE_FILTERS"`
+	Exclude []string `yaml:"exclude" env:"REPO_NAME_EXCLUDE_FILTERS"`
+}
+
+// TitlesConfig post titles by repo name and the casing of words in generated titles
+type TitlesConfig struct {
+	Mappings   map[string]string `yaml:"mappings" env:"REPO_NAME_TO_POST_TITLE_MAPPINGS"`
+	WordCasing []string          `yaml:"wordCasing" env:"WORDS_TO_CORRECT_CASING_LIST"`
+}
+
+// SummariesConfig words a post summary starts with, one picked at random per post
+type SummariesConfig struct {
+	Prefixes []string `yaml:"prefixes" env:"RANDOM_SUMMARY_PREFIX_LIST"`
+}
+
+// TagsConfig how post tags are picked and renamed
+type TagsConfig struct {
+	AutoIfInRepoName []string            `yaml:"autoIfInRepoName" env:"AUTO_TAGS_IF_IN_REPO_NAME"`
+	Static           []string            `yaml:"static" env:"STATIC_TAGS"`
+	RepoNameMappings map[string][]string `yaml:"repoNameMappings" env:"REPO_NAME_TAG_MAPPINGS"`
+	Rename           map[string]string   `yaml:"rename" env:"TAG_MAP_JSON"`
+	Rules            []TagRule           `yaml:"rules" env:"TAG_RULES_JSON"`
+}
+
+// BodyConfig how a README becomes a post body
+type BodyConfig struct {
+	GFMTransforms             []string            `yaml:"gfmTransforms" env:"GFM_TRANSFORMS"`
+	GFMShortcodes             map[string]string   `yaml:"gfmShortcodes" env:"GFM_SHORTCODE_MAPPINGS"`
+	ExpandSourceLinks         bool                `yaml:"expandSourceLinks" env:"EXPAND_SOURCE_LINKS"`
+	ExpandSourceLinksMaxBytes int                 `yaml:"expandSourceLinksMaxBytes" env:"EXPAND_SOURCE_LINKS_MAX_BYTES"`
+	TOCMode                   string              `yaml:"tocMode" env:"TOC_MODE"`
+	ReadingWordsPerMinute     int                 `yaml:"readingWordsPerMinute" env:"READING_WORDS_PER_MINUTE"`
+	SanitizerPolicy           HTMLSanitizerPolicy `yaml:"sanitizerPolicy" env:"HTML_SANITIZER_POLICY_JSON"`
+	CleanupRules              []string            `yaml:"cleanupRules" env:"BODY_CLEANUP_RULES"`
+	BoilerplateFingerprints   []string            `yaml:"boilerplateFingerprints" env:"BOILERPLATE_FINGERPRINTS"`
+	StripPatterns             []string            `yaml:"stripPatterns" env:"BODY_STRIP_PATTERNS"`
+}
+
+// TemplatesConfig which template renders a post
+type TemplatesConfig struct {
+	Directory string         `yaml:"directory"`
+	Default   string         `yaml:"default" env:"TEMPLATE_DEFAULT"`
+	Rules     []TemplateRule `yaml:"rules" env:"TEMPLATE_RULES_JSON"`
+}
+
+// OutputsConfig where posts and the rest of the site are written and how
+type OutputsConfig struct {
+	Target                  string            `yaml:"target"`
+	FrontMatterFormat       string            `yaml:"frontMatterFormat"`
+	DestinationDirectory    string            `yaml:"destinationDirectory"`
+	SiteDirectory           string            `yaml:"siteDirectory"`
+	PostURLPattern          string            `yaml:"postURLPattern" env:"POST_URL_PATTERN"`
+	SiteBaseURL             string            `yaml:"siteBaseURL" env:"SITE_BASE_URL"`
+	HTMLSiteTitle           string            `yaml:"htmlSiteTitle" env:"HTML_SITE_TITLE"`
+	SlugHistoryFile         string            `yaml:"slugHistoryFile" env:"SLUG_HISTORY_FILE"`
+	GeneratedPostsStateFile string            `yaml:"generatedPostsStateFile" env:"GENERATED_POSTS_STATE_FILE"`
+	Taxonomy                TaxonomyConfig    `yaml:"taxonomy"`
+	Search                  SearchConfig      `yaml:"search"`
+	Feeds                   FeedsConfig       `yaml:"feeds"`
+	SocialCards             SocialCardsConfig `yaml:"socialCards"`
+	Publish                 PublishConfig     `yaml:"publish"`
+}
+
+// TaxonomyConfig term descriptions and whether terms get landing pages
+type TaxonomyConfig struct {
+	TagDescriptions      map[string]string `yaml:"tagDescriptions" env:"TAG_DESCRIPTIONS_JSON"`
+	CategoryDescriptions map[string]string `yaml:"categoryDescriptions" env:"CATEGORY_DESCRIPTIONS_JSON"`
+	IndexPages           bool              `yaml:"indexPages" env:"TAXONOMY_INDEX_PAGES"`
+}
+
+// SearchConfig weights of the search index fields and the length of result excerpts
+type SearchConfig struct {
+	FieldBoosts  map[string]float64 `yaml:"fieldBoosts" env:"SEARCH_INDEX_FIELD_BOOSTS"`
+	ExcerptWords int                `yaml:"excerptWords" env:"SEARCH_INDEX_EXCERPT_WORDS"`
+}
+
+// FeedsConfig the RSS, Atom and JSON feeds
+type FeedsConfig struct {
+	Title     string   `yaml:"title" env:"FEED_TITLE"`
+	Formats   []string `yaml:"formats" env:"FEED_FORMATS"`
+	ItemLimit *int     `yaml:"itemLimit" env:"FEED_ITEM_LIMIT"`
+	Content   string   `yaml:"content" env:"FEED_CONTENT"`
+	Sort      string   `yaml:"sort" env:"FEED_SORT"`
+}
+
+// SocialCardsConfig the Open Graph images rendered for posts
+type SocialCardsConfig struct {
+	Mode            string           `yaml:"mode" env:"SOCIAL_CARDS"`
+	StaticDirectory string           `yaml:"staticDirectory" env:"SOCIAL_CARD_STATIC_DIRECTORY"`
+	URLPrefix       string           `yaml:"urlPrefix" env:"SOCIAL_CARD_URL_PREFIX"`
+	Layout          SocialCardLayout `yaml:"layout" env:"SOCIAL_CARD_LAYOUT_JSON"`
+}
+
+// PublishConfig the git repo posts are published to
+type PublishConfig struct {
+	RepoURL       string `yaml:"repoURL" env:"PUBLISH_REPO_URL"`
+	Branch        string `yaml:"branch" env:"PUBLISH_BRANCH"`
+	ContentPath   string `yaml:"contentPath" env:"PUBLISH_CONTENT_PATH"`
+	WorkDirectory string `yaml:"workDirectory" env:"PUBLISH_WORK_DIRECTORY"`
+	Push          bool   `yaml:"push" env:"PUBLISH_PUSH"`
+	AuthorName    string `yaml:"authorName" env:"PUBLISH_GIT_AUTHOR_NAME"`
+	AuthorEmail   string `yaml:"authorEmail" env:"PUBLISH_GIT_AUTHOR_EMAIL"`
+	CommitSubject string `yaml:"commitSubject" env:"PUBLISH_COMMIT_SUBJECT"`
+}
+
+// LinkCheckConfig how hard check-links hits link hosts
+type LinkCheckConfig struct {
+	Concurrency    int  `yaml:"concurrency" env:"LINK_CHECK_CONCURRENCY"`
+	HostIntervalMS *int `yaml:"hostIntervalMS" env:"LINK_CHECK_HOST_INTERVAL_MS"`
+	TimeoutMS      *int `yaml:"timeoutMS" env:"LINK_CHECK_TIMEOUT_MS"`
+	// BrokenCacheTTLMinutes how long a broken link isn't requested again
+	BrokenCacheTTLMinutes *int `yaml:"brokenCacheTTLMinutes" env:"LINK_CHECK_BROKEN_CACHE_TTL_MINUTES"`
+}
+
+// ConfigOverrides -set key=value flags. e.g. -set outputs.feeds.itemLimit=10 -set 'tags.static=[aws, serverless]'
+type ConfigOverrides []string
+
+func (overrides *ConfigOverrides) String() string {
+	return strings.Join(*overrides, " ")
+}
+
+// Set adds an override, called by flag for every -set
+func (overrides *ConfigOverrides) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	*overrides = append(*overrides, value)
+	return nil
+}
+
+// defaultConfig the config before the config file is read. maps are fresh so loading never changes the defaults
+func defaultConfig() *Config {
+	fieldBoosts := make(map[string]float64)
+	for field, boost := range defaultSearchIndexFieldBoosts {
+		fieldBoosts[field] = boost
+	}
+	return &Config{
+		Outputs: OutputsConfig{
+			Search:      SearchConfig{FieldBoosts: fieldBoosts},
+			SocialCards: SocialCardsConfig{Layout: defaultSocialCardLayout},
+		},
+	}
+}
+
+// ConfigSources where the value of each key was set. e.g. filters.include[1] -> config.yaml:4:14,
+// tags.static -> STATIC_TAGS or outputs.feeds.itemLimit -> -set outputs.feeds.itemLimit
+type ConfigSources struct {
+	positions map[string]string
+	// keys a .env variable or -set replaced as a whole, the keys below them share their position
+	whole map[string]bool
+	// the line of every key of the config file. e.g. config.yaml:4
+	keyLines map[string]string
+}
+
+func newConfigSources() ConfigSources {
+	return ConfigSources{positions: make(map[string]string), whole: make(map[string]bool), keyLines: make(map[string]string)}
+}
+
+// set replaces the source of key and of everything below it
+func (sources ConfigSources) set(key string, position string) {
+	sources.clearBelow(key)
+	sources.positions[key] = position
+	sources.whole[key] = true
+}
+
+// clearBelow forgets the sources of the keys below key
+func (sources ConfigSources) clearBelow(key string) {
+	for existing := range sources.positions {
+		if strings.HasPrefix(existing, key+".") || strings.HasPrefix(existing, key+"[") {
+			delete(sources.positions, existing)
+		}
+	}
+}
+
+// position the source of key or of the key above it that was set as a whole
+func (sources ConfigSources) position(key string) string {
+	if position, ok := sources.positions[key]; ok {
+		return position
+	}
+	for key != "" {
+		key = strings.TrimRight(key[:strings.LastIndexAny(key, ".[")+1], ".[")
+		if sources.whole[key] {
+			return sources.positions[key]
+		}
+	}
+	return "default"
+}
+
+// keyAtLine the innermost key written on line of the config file
+func (sources ConfigSources) keyAtLine(path string, line int) string {
+	keyLine := fmt.Sprintf("%s:%d", path, line)
+	found := ""
+	for key, position := range sources.keyLines {
+		if position == keyLine && (len(key) > len(found) || len(key) == len(found) && key < found) {
+			found = key
+		}
+	}
+	return found
+}
+
+func joinConfigKey(parent string, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// getConfigPath the -config flag or config.yaml when it exists
+func getConfigPath() (string, bool) {
+	if configPath != "" {
+		return configPath, true
+	}
+	return defaultConfigFileName, false
+}
+
+// readConfig reads the config file, then the .env variables, then the -profile section of the file, then the -set flags.
+// each one only replaces the keys it sets. keys that can't be parsed are skipped and returned as problems
+func readConfig() (*Config, ConfigSources, []ConfigProblem) {
+	config := defaultConfig()
+	sources := newConfigSources()
+	problems := make([]ConfigProblem, 0)
+
+	path, required := getConfigPath()
+	b, err := ioutil.ReadFile(path)
+	if err != nil && (required || !os.IsNotExist(err)) {
+		problems = append(problems, ConfigProblem{Severity: configProblemError, Position: path, Message: err.Error()})
+	}
+	if len(bytes.TrimSpace(b)) > 0 {
+		problems = append(problems, decodeConfigFile(path, b, config, sources)...)
+	}
+	problems = append(problems, applyEnvConfig(reflect.ValueOf(config).Elem(), "", sources)...)
+
+	if profile != "" {
+		problems = append(problems, applyConfigProfile(path, b, profile, config, sources)...)
+	}
+
+	for _, override := range configSets {
+		parts := strings.SplitN(override, "=", 2)
+		position := "-set " + parts[0]
+		if err := setConfigValue(config, parts[0], parts[1]); err != nil {
+			problems = append(problems, ConfigProblem{Severity: configProblemError, Key: parts[0], Position: position, Message: err.Error()})
+			continue
+		}
+		sources.set(parts[0], position)
+	}
+	return config, sources, problems
+}
+
+// loadConfig the config or the first key that couldn't be parsed
+func loadConfig() (*Config, error) {
+	config, _, problems := readConfig()
+	if len(problems) > 0 {
+		return config, problems[0]
+	}
+	return config, nil
+}
+
+// loadedConfig the config checkConfig loaded for the command and profile being run
+var loadedConfig *Config
+
+// getConfig the config loaded by checkConfig, otherwise the config as it is now.
+// loading errors are reported by main before any command runs, here they are only logged
+func getConfig() *Config {
+	if loadedConfig != nil {
+		return loadedConfig
+	}
+	config, err := loadConfig()
+	if err != nil {
+		log.Warnf("loadConfig() failed: %v\n", err)
+	}
+	return config
+}
+
+var yamlLineRegexp = regexp.MustCompile(`line (\d+): (.*)`)
+var yamlUnknownFieldRegexp = regexp.MustCompile(`^field (\S+) not found in type .*`)
+
+// decodeConfigFile decodes the config file into config and records the line and column of every key
+func decodeConfigFile(path string, b []byte, config *Config, sources ConfigSources) []ConfigProblem {
+	var document yaml.Node
+	if err := yaml.Unmarshal(b, &document); err != nil {
+		return []ConfigProblem{newYAMLProblem(path, strings.TrimPrefix(err.Error(), "yaml: "), sources)}
+	}
+	recordYAMLSources(&document, "", path, sources)
+
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	decoder.KnownFields(true)
+	err := decoder.Decode(config)
+	if err == nil {
+		return nil
+	}
+	// a type error doesn't stop the rest of the file from being decoded
+	if typeError, ok := err.(*yaml.TypeError); ok {
+		problems := make([]ConfigProblem, 0, len(typeError.Errors))
+		for _, message := range typeError.Errors {
+			problems = append(problems, newYAMLProblem(path, message, sources))
+		}
+		return problems
+	}
+	return []ConfigProblem{newYAMLProblem(path, strings.TrimPrefix(err.Error(), "yaml: "), sources)}
+}
+
+// newYAMLProblem turns "line 3: cannot unmarshal ..." into a problem at config.yaml:3 with the key on that line
+func newYAMLProblem(path string, message string, sources ConfigSources) ConfigProblem {
+	problem := ConfigProblem{Severity: configProblemError, Position: path, Message: message}
+	if match := yamlLineRegexp.FindStringSubmatch(message); match != nil {
+		line, _ := strconv.Atoi(match[1])
+		problem.Position = fmt.Sprintf("%s:%d", path, line)
+		problem.Key = sources.keyAtLine(path, line)
+		problem.Message = yamlUnknownFieldRegexp.ReplaceAllString(match[2], "unknown key $1")
+	}
+	return problem
+}
+
+// applyConfigProfile lays the keys of a profile over the keys outside of profiles. lists are replaced, maps are merged
+func applyConfigProfile(path string, b []byte, name string, config *Config, sources ConfigSources) []ConfigProblem {
+	var document yaml.Node
+	if err := yaml.Unmarshal(b, &document); err != nil {
+		// reported by decodeConfigFile
+		return nil
+	}
+	node := findConfigProfileNode(&document, name)
+	if node == nil {
+		return []ConfigProblem{{Severity: configProblemError, Key: "profiles", Position: "-profile " + name, Message: fmt.Sprintf("unknown profile %q", name)}}
+	}
+	recordYAMLSources(node, "", path, sources)
+	// unknown keys and type errors in the profile were reported when the whole file was decoded
+	node.Decode(config)
+	return nil
+}
+
+// findConfigProfileNode the profiles.<name> mapping of the config file or nil
+func findConfigProfileNode(document *yaml.Node, name string) *yaml.Node {
+	if len(document.Content) == 0 {
+		return nil
+	}
+	node := document.Content[0]
+	for _, key := range []string{"profiles", name} {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var value *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				value = node.Content[i+1]
+			}
+		}
+		if value == nil {
+			return nil
+		}
+		node = value
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	return node
+}
+
+// getConfigProfileNames the profiles of the config file in name order
+func getConfigProfileNames() ([]string, error) {
+	config, _, problems := readConfig()
+	for _, problem := range problems {
+		if problem.Key == "profiles" || strings.HasPrefix(problem.Key, "profiles.") {
+			return nil, problem
+		}
+	}
+	if len(config.Profiles) == 0 {
+		path, _ := getConfigPath()
+		return nil, fmt.Errorf("no profiles in %s", path)
+	}
+	return sortedKeys(config.Profiles), nil
+}
+
+func recordYAMLSources(node *yaml.Node, key string, path string, sources ConfigSources) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			recordYAMLSources(child, key, path, sources)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			childKey := joinConfigKey(key, node.Content[i].Value)
+			value := node.Content[i+1]
+			if value.Kind != yaml.MappingNode {
+				// a profile replaces the list of the same key, so the items past the end of its list are gone
+				sources.clearBelow(childKey)
+			}
+			sources.keyLines[childKey] = fmt.Sprintf("%s:%d", path, node.Content[i].Line)
+			sources.positions[childKey] = fmt.Sprintf("%s:%d:%d", path, value.Line, value.Column)
+			recordYAMLSources(value, childKey, path, sources)
+		}
+	case yaml.SequenceNode:
+		for i, value := range node.Content {
+			childKey := fmt.Sprintf("%s[%d]", key, i)
+			sources.positions[childKey] = fmt.Sprintf("%s:%d:%d", path, value.Line, value.Column)
+			recordYAMLSources(value, childKey, path, sources)
+		}
+	}
+}
+
+// applyEnvConfig the compatibility loader for .env files. every field with an env tag is set when its variable isn't empty
+func applyEnvConfig(v reflect.Value, key string, sources ConfigSources) []ConfigProblem {
+	problems := make([]ConfigProblem, 0)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldKey := joinConfigKey(key, strings.Split(field.Tag.Get("yaml"), ",")[0])
+		name := field.Tag.Get("env")
+		if name == "" {
+			if field.Type.Kind() == reflect.Struct {
+				problems = append(problems, applyEnvConfig(v.Field(i), fieldKey, sources)...)
+			}
+			continue
+		}
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		if err := setEnvConfigValue(v.Field(i), value); err != nil {
+			problems = append(problems, ConfigProblem{Severity: configProblemError, Key: fieldKey, Position: name, Message: err.Error()})
+			continue
+		}
+		sources.set(fieldKey, name)
+		if items, ok := v.Field(i).Interface().([]string); ok {
+			for j := range items {
+				sources.positions[fmt.Sprintf("%s[%d]", fieldKey, j)] = fmt.Sprintf("%s item %d", name, j+1)
+			}
+		}
+	}
+	return problems
+}
+
+// setEnvConfigValue parses the formats the .env variables have always used.
+// comma separated lists, name=tag,tag|name=tag mappings, field=boost,field=boost and JSON for everything else
+func setEnvConfigValue(v reflect.Value, value string) error {
+	switch v.Interface().(type) {
+	case string:
+		v.SetString(value)
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case *int:
+		// 0 is a value of its own, not the absence of one
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(&n))
+	case []string:
+		v.Set(reflect.ValueOf(strings.Split(value, ",")))
+	case map[string][]string:
+		mappings := make(map[string][]string)
+		for _, mapping := range strings.Split(value, "|") {
+			parts := strings.SplitN(mapping, "=", 2)
+			if len(parts) < 2 {
+				continue
+			}
+			for _, tag := range strings.Split(parts[1], ",") {
+				if tag != "" {
+					mappings[parts[0]] = append(mappings[parts[0]], tag)
+				}
+			}
+		}
+		v.Set(reflect.ValueOf(mappings))
+	case map[string]float64:
+		values := make(map[string]float64)
+		for _, item := range strings.Split(value, ",") {
+			parts := strings.SplitN(item, "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("expected name=number, got %q", item)
+			}
+			f, err := strconv.ParseFloat(parts[1], 64)
+			if err != nil {
+				return fmt.Errorf("expected name=number, got %q", item)
+			}
+			values[strings.ToLower(parts[0])] = f
+		}
+		// replaces the defaults like a list in the config file would
+		v.Set(reflect.ValueOf(values))
+	default:
+		// structs overlay their current value so fields that aren't set keep it
+		if err := json.Unmarshal([]byte(value), v.Addr().Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findConfigField the struct field whose yaml name is name
+func findConfigField(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if strings.EqualFold(strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0], name) {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// setConfigValue sets a dotted key to a YAML value. the part of a key past a map is the map key.
+// e.g. outputs.feeds.itemLimit=10, tags.static=[aws, go] or titles.mappings.aws-cdk-playground=AWS CDK
+func setConfigValue(config *Config, key string, value string) error {
+	v := reflect.ValueOf(config).Elem()
+	names := strings.Split(key, ".")
+	for i, name := range names {
+		if v.Kind() == reflect.Map {
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setYAMLValue(elem, value); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(strings.Join(names[i:], ".")), elem)
+			return nil
+		}
+		field, ok := reflect.Value{}, false
+		if v.Kind() == reflect.Struct {
+			field, ok = findConfigField(v, name)
+		}
+		if !ok {
+			return fmt.Errorf("unknown key %q", strings.Join(names[:i+1], "."))
+		}
+		v = field
+	}
+	return setYAMLValue(v, value)
+}
+
+// setYAMLValue strings are taken as is so values like /{slug}/ don't need quoting
+func setYAMLValue(v reflect.Value, value string) error {
+	if v.Kind() == reflect.String {
+		v.SetString(value)
+		return nil
+	}
+	return yaml.Unmarshal([]byte(value), v.Addr().Interface())
+}
+
+// givenFlags the flags given on the command line, set by main before the config changes any of them
+var givenFlags = make(map[string]bool)
+
+// applyConfigToFlags config keys that have a flag are used when the flag isn't given.
+// flags the config leaves empty go back to their default so one profile doesn't inherit the values of the one before it
+func applyConfigToFlags(config *Config) {
+	for name, value := range map[string]string{
+		"user":                  config.GitHub.User,
+		"target":                config.Outputs.Target,
+		"front-matter-format":   config.Outputs.FrontMatterFormat,
+		"destination-directory": config.Outputs.DestinationDirectory,
+		"site-directory":        config.Outputs.SiteDirectory,
+		"template-dir":          config.Templates.Directory,
+	} {
+		if givenFlags[name] {
+			continue
+		}
+		if value == "" {
+			value = flag.Lookup(name).DefValue
+		}
+		flag.Set(name, value)
+	}
+}
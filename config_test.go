@@ -0,0 +1,325 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testConfigYAML = `
+filters:
+  include: [".*-playground"]
+titles:
+  mappings:
+    aws-well-architected-playground: AWS Well-Architected
+tags:
+  static: [experiment]
+  repoNameMappings:
+    lambda-playground: [aws, serverless]
+outputs:
+  feeds:
+    itemLimit: 0
+  socialCards:
+    layout:
+      background: "#ffffff"
+`
+
+// getConfigEnvNames the .env variables of every key below t
+func getConfigEnvNames(t reflect.Type) []string {
+	names := make([]string, 0)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if name := field.Tag.Get("env"); name != "" {
+			names = append(names, name)
+		} else if field.Type.Kind() == reflect.Struct {
+			names = append(names, getConfigEnvNames(field.Type)...)
+		}
+	}
+	return names
+}
+
+// withTestConfig uses yaml as the config file. .env variables are cleared for the test, godotenv/autoload sets them from .env
+func withTestConfig(t *testing.T, yaml string, sets ...string) {
+	for _, name := range getConfigEnvNames(reflect.TypeOf(Config{})) {
+		t.Setenv(name, "")
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(path, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configPath, configSets = path, sets
+	t.Cleanup(func() { configPath, configSets, loadedConfig = "", nil, nil })
+}
+
+func TestLoadConfig(t *testing.T) {
+	withTestConfig(t, testConfigYAML)
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Titles.Mappings["aws-well-architected-playground"] != "AWS Well-Architected" {
+		t.Errorf("unexpected title mappings %v", config.Titles.Mappings)
+	}
+	if !reflect.DeepEqual(config.Tags.RepoNameMappings["lambda-playground"], []string{"aws", "serverless"}) {
+		t.Errorf("unexpected tag mappings %v", config.Tags.RepoNameMappings)
+	}
+	if config.Outputs.Feeds.ItemLimit == nil || *config.Outputs.Feeds.ItemLimit != 0 {
+		t.Errorf("expected an item limit of 0, got %v", config.Outputs.Feeds.ItemLimit)
+	}
+	// keys of a section that aren't in the file keep their default
+	layout := config.Outputs.SocialCards.Layout
+	if layout.Background != "#ffffff" || layout.Width != defaultSocialCardLayout.Width {
+		t.Errorf("unexpected layout %+v", layout)
+	}
+	if config.Outputs.Search.FieldBoosts["title"] != defaultSearchIndexFieldBoosts["title"] {
+		t.Errorf("expected the default field boosts, got %v", config.Outputs.Search.FieldBoosts)
+	}
+}
+
+func TestLoadConfigUnknownKey(t *testing.T) {
+	withTestConfig(t, "tags:\n  statik: [aws]\n")
+	if _, err := loadConfig(); err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	configPath = filepath.Join(t.TempDir(), "missing.yaml")
+	defer func() { configPath = "" }()
+	if _, err := loadConfig(); err == nil {
+		t.Error("expected an error for a missing -config file")
+	}
+}
+
+func TestLoadConfigEnvOverrides(t *testing.T) {
+	withTestConfig(t, testConfigYAML)
+	os.Setenv("STATIC_TAGS", "aws,serverless")
+	os.Setenv("REPO_NAME_TAG_MAPPINGS", "alexa-skills-playground=aws,alexa|storybook-playground=react,ui,|broken")
+	os.Setenv("SEARCH_INDEX_FIELD_BOOSTS", "Title=20,body=0.5")
+	os.Setenv("SOCIAL_CARD_LAYOUT_JSON", `{"showStars": true}`)
+	defer func() {
+		for _, key := range []string{"STATIC_TAGS", "REPO_NAME_TAG_MAPPINGS", "SEARCH_INDEX_FIELD_BOOSTS", "SOCIAL_CARD_LAYOUT_JSON"} {
+			os.Unsetenv(key)
+		}
+	}()
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(config.Tags.Static, []string{"aws", "serverless"}) {
+		t.Errorf("unexpected static tags %v", config.Tags.Static)
+	}
+	wantMappings := map[string][]string{"alexa-skills-playground": {"aws", "alexa"}, "storybook-playground": {"react", "ui"}}
+	if !reflect.DeepEqual(config.Tags.RepoNameMappings, wantMappings) {
+		t.Errorf("expected %v, got %v", wantMappings, config.Tags.RepoNameMappings)
+	}
+	if !reflect.DeepEqual(config.Outputs.Search.FieldBoosts, map[string]float64{"title": 20, "body": 0.5}) {
+		t.Errorf("unexpected field boosts %v", config.Outputs.Search.FieldBoosts)
+	}
+	layout := config.Outputs.SocialCards.Layout
+	if !layout.ShowStars || layout.Background != "#ffffff" {
+		t.Errorf("expected the env layout on top of the file layout, got %+v", layout)
+	}
+	// keys the env doesn't set come from the file
+	if !reflect.DeepEqual(config.Filters.Include, []string{".*-playground"}) {
+		t.Errorf("unexpected include filters %v", config.Filters.Include)
+	}
+}
+
+func TestLoadConfigEnvInvalid(t *testing.T) {
+	os.Setenv("READING_WORDS_PER_MINUTE", "fast")
+	defer os.Unsetenv("READING_WORDS_PER_MINUTE")
+	if _, err := loadConfig(); err == nil {
+		t.Error("expected an error for a non numeric READING_WORDS_PER_MINUTE")
+	}
+}
+
+func TestLoadConfigSetOverrides(t *testing.T) {
+	withTestConfig(t, testConfigYAML,
+		"outputs.feeds.itemLimit=5",
+		"tags.static=[aws, go]",
+		"titles.mappings.aws-cdk-playground=AWS CDK",
+		"outputs.postURLPattern=/{year}/{slug}/",
+		"body.expandSourceLinks=true",
+	)
+	os.Setenv("STATIC_TAGS", "serverless")
+	defer os.Unsetenv("STATIC_TAGS")
+
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *config.Outputs.Feeds.ItemLimit != 5 {
+		t.Errorf("expected an item limit of 5, got %d", *config.Outputs.Feeds.ItemLimit)
+	}
+	if !reflect.DeepEqual(config.Tags.Static, []string{"aws", "go"}) {
+		t.Errorf("expected -set to win over the env, got %v", config.Tags.Static)
+	}
+	if len(config.Titles.Mappings) != 2 || config.Titles.Mappings["aws-cdk-playground"] != "AWS CDK" {
+		t.Errorf("unexpected title mappings %v", config.Titles.Mappings)
+	}
+	if config.Outputs.PostURLPattern != "/{year}/{slug}/" || !config.Body.ExpandSourceLinks {
+		t.Errorf("unexpected config %+v %+v", config.Outputs, config.Body)
+	}
+}
+
+func TestSetConfigValueUnknownKey(t *testing.T) {
+	for _, key := range []string{"tags.statik", "outputs.feeds.itemLimit.max", "nope"} {
+		if err := setConfigValue(defaultConfig(), key, "1"); err == nil {
+			t.Errorf("expected an error for %s", key)
+		}
+	}
+}
+
+func TestLoadConfigSample(t *testing.T) {
+	b, err := ioutil.ReadFile("config.sample.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withTestConfig(t, string(b))
+	config, err := loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Outputs.Target != hugoTarget || config.Tags.Rename["js"] != "javascript" {
+		t.Errorf("unexpected config %+v", config)
+	}
+}
+
+const testProfilesConfigYAML = `
+github:
+  user: pfeilbr
+filters:
+  include: [".*-playground", ".*-example"]
+titles:
+  mappings:
+    aws-well-architected-playground: AWS Well-Architected
+outputs:
+  destinationDirectory: ../blog/content/post
+profiles:
+  blog: {}
+  team:
+    github:
+      user: acme
+    filters:
+      include: ["^kb-"]
+    titles:
+      mappings:
+        kb-onboarding: Onboarding
+    outputs:
+      destinationDirectory: ../kb/content/post
+`
+
+func TestLoadConfigProfile(t *testing.T) {
+	withTestConfig(t, testProfilesConfigYAML)
+	profile = "team"
+	defer func() { profile = "" }()
+
+	config, sources, problems := readConfig()
+	if len(problems) > 0 {
+		t.Fatal(problems)
+	}
+	if config.GitHub.User != "acme" || config.Outputs.DestinationDirectory != "../kb/content/post" {
+		t.Errorf("expected the keys of the profile, got %+v %+v", config.GitHub, config.Outputs)
+	}
+	if !reflect.DeepEqual(config.Filters.Include, []string{"^kb-"}) {
+		t.Errorf("expected the profile to replace the include filters, got %v", config.Filters.Include)
+	}
+	if len(config.Titles.Mappings) != 2 {
+		t.Errorf("expected the profile mappings merged with the others, got %v", config.Titles.Mappings)
+	}
+	if position := sources.position("filters.include[0]"); position != configPath+":17:17" {
+		t.Errorf("expected the position in the profile, got %s", position)
+	}
+	if position := sources.position("filters.include[1]"); position != "default" {
+		t.Errorf("expected the replaced filter to be gone, got %s", position)
+	}
+}
+
+func TestLoadConfigProfileOverEnv(t *testing.T) {
+	withTestConfig(t, testProfilesConfigYAML, "outputs.destinationDirectory=out")
+	t.Setenv("GITHUB_USERNAME", "pfeilbr")
+	profile = "team"
+	defer func() { profile = "" }()
+
+	config, sources, problems := readConfig()
+	if len(problems) > 0 {
+		t.Fatal(problems)
+	}
+	if config.GitHub.User != "acme" || sources.position("github.user") != configPath+":15:13" {
+		t.Errorf("expected the profile to replace the .env user, got %s from %s", config.GitHub.User, sources.position("github.user"))
+	}
+	if config.Outputs.DestinationDirectory != "out" {
+		t.Errorf("expected -set to replace the profile, got %s", config.Outputs.DestinationDirectory)
+	}
+}
+
+func TestLoadConfigUnknownProfile(t *testing.T) {
+	withTestConfig(t, testProfilesConfigYAML)
+	profile = "docs"
+	defer func() { profile = "" }()
+	if _, err := loadConfig(); err == nil || !strings.Contains(err.Error(), `unknown profile "docs"`) {
+		t.Errorf("expected an unknown profile error, got %v", err)
+	}
+}
+
+func TestGetConfigProfileNames(t *testing.T) {
+	withTestConfig(t, testProfilesConfigYAML)
+	names, err := getConfigProfileNames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(names, []string{"blog", "team"}) {
+		t.Errorf("unexpected profiles %v", names)
+	}
+
+	withTestConfig(t, testConfigYAML)
+	if _, err := getConfigProfileNames(); err == nil {
+		t.Error("expected an error for a config without profiles")
+	}
+}
+
+func TestApplyConfigToFlagsProfiles(t *testing.T) {
+	withTestConfig(t, testProfilesConfigYAML)
+	givenFlags["user"] = true
+	defer func() {
+		profile, user, destinationDirectory = "", "", ""
+		delete(givenFlags, "user")
+	}()
+
+	user = "octocat"
+	for _, test := range []struct{ profile, destinationDirectory string }{
+		{"team", "../kb/content/post"},
+		{"blog", "../blog/content/post"},
+	} {
+		profile = test.profile
+		config, err := loadConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		applyConfigToFlags(config)
+		if destinationDirectory != test.destinationDirectory || user != "octocat" {
+			t.Errorf("%s: unexpected flags %s %s", test.profile, destinationDirectory, user)
+		}
+	}
+
+	config := defaultConfig()
+	applyConfigToFlags(config)
+	if destinationDirectory != "" {
+		t.Errorf("expected the flag default when the config leaves it empty, got %s", destinationDirectory)
+	}
+}
+
+func TestGetConfigLoadedOnce(t *testing.T) {
+	withTestConfig(t, testConfigYAML)
+	config, _ := checkConfig()
+	os.Setenv("STATIC_TAGS", "aws")
+	defer os.Unsetenv("STATIC_TAGS")
+	if getConfig() != config {
+		t.Error("expected the config checkConfig loaded")
+	}
+}
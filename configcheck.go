@@ -0,0 +1,490 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const configProblemError = "error"
+const configProblemWarning = "warning"
+
+// repo names a filter is tried on to tell whether it matches every repo
+var everyRepoNameProbes = []string{"", "x", "-", "my-repo-playground"}
+
+var readmeFeatureNames = []string{readmeFeatureCode, readmeFeatureImages, readmeFeatureMermaid, readmeFeatureHeadings, readmeFeatureTables}
+
+// ConfigProblem a key that can't be used as it is or that probably doesn't do what was intended
+type ConfigProblem struct {
+	Severity string `json:"severity"`
+	Key      string `json:"key,omitempty"`
+	Position string `json:"position"`
+	Message  string `json:"message"`
+	// Profile the profile the problem was found in when it only shows with that profile laid over the config
+	Profile string `json:"profile,omitempty"`
+}
+
+func (problem ConfigProblem) describe() string {
+	description := problem.Message
+	if problem.Key != "" {
+		description = problem.Key + ": " + description
+	}
+	if problem.Profile != "" {
+		description = "profile " + problem.Profile + ": " + description
+	}
+	return description
+}
+
+// Error e.g. config.yaml:4:14: filters.include[1]: error parsing regexp: missing closing ): `(`
+func (problem ConfigProblem) Error() string {
+	return fmt.Sprintf("%s: %s", problem.Position, problem.describe())
+}
+
+func (problem ConfigProblem) String() string {
+	return fmt.Sprintf("%s: %s: %s", problem.Position, problem.Severity, problem.describe())
+}
+
+// configChecker collects the problems of a config with the position each key was set at
+type configChecker struct {
+	sources  ConfigSources
+	problems []ConfigProblem
+}
+
+func (checker *configChecker) add(severity string, key string, format string, args ...interface{}) {
+	checker.problems = append(checker.problems, ConfigProblem{
+		Severity: severity,
+		Key:      key,
+		Position: checker.sources.position(key),
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (checker *configChecker) errorf(key string, format string, args ...interface{}) {
+	checker.add(configProblemError, key, format, args...)
+}
+
+func (checker *configChecker) warnf(key string, format string, args ...interface{}) {
+	checker.add(configProblemWarning, key, format, args...)
+}
+
+// oneOf an empty value is left to the default
+func (checker *configChecker) oneOf(key string, value string, allowed ...string) {
+	if value != "" && !contains(allowed, value) {
+		checker.errorf(key, "unknown value %q, expected one of %s", value, strings.Join(allowed, ", "))
+	}
+}
+
+func (checker *configChecker) notNegative(key string, value int) {
+	if value < 0 {
+		checker.errorf(key, "must be 0 or more, got %d", value)
+	}
+}
+
+func (checker *configChecker) regexp(key string, pattern string) *regexp.Regexp {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		checker.errorf(key, "%v", err)
+		return nil
+	}
+	return re
+}
+
+func (checker *configChecker) absoluteURL(key string, value string) {
+	if value == "" {
+		return
+	}
+	if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+		checker.errorf(key, "expected an absolute URL, got %q", value)
+	}
+}
+
+func matchesEveryRepoName(re *regexp.Regexp) bool {
+	for _, name := range everyRepoNameProbes {
+		if !re.MatchString(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// compileConfigRegexps the patterns of a list key. the error names the key and the index of the pattern
+func compileConfigRegexps(key string, patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for i, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %v", key, i, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := make([]string, 0)
+	switch m := m.(type) {
+	case map[string]string:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string][]string:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]float64:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]Config:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]interface{}:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (checker *configChecker) checkFilters(filters FiltersConfig) {
+	if len(filters.Include) == 0 {
+		checker.warnf("filters.include", "no include filters, every repo becomes a post")
+	}
+	for i, pattern := range filters.Include {
+		key := fmt.Sprintf("filters.include[%d]", i)
+		if strings.TrimSpace(pattern) == "" {
+			checker.warnf(key, "empty filter matches every repo")
+		} else if re := checker.regexp(key, pattern); re != nil && matchesEveryRepoName(re) {
+			checker.warnf(key, "%q matches every repo", pattern)
+		}
+	}
+	for i, pattern := range filters.Exclude {
+		key := fmt.Sprintf("filters.exclude[%d]", i)
+		if strings.TrimSpace(pattern) == "" {
+			checker.warnf(key, "empty filter excludes every repo")
+		} else if re := checker.regexp(key, pattern); re != nil && matchesEveryRepoName(re) {
+			checker.warnf(key, "%q excludes every repo", pattern)
+		}
+	}
+}
+
+func (checker *configChecker) checkTitlesAndTags(config *Config) {
+	for _, name := range sortedKeys(config.Titles.Mappings) {
+		if strings.TrimSpace(config.Titles.Mappings[name]) == "" {
+			checker.errorf("titles.mappings."+name, "empty title")
+		}
+	}
+	for _, name := range sortedKeys(config.Tags.RepoNameMappings) {
+		key := "tags.repoNameMappings." + name
+		if len(config.Tags.RepoNameMappings[name]) == 0 {
+			checker.warnf(key, "no tags")
+		}
+		for i, tag := range config.Tags.RepoNameMappings[name] {
+			if strings.TrimSpace(tag) == "" {
+				checker.errorf(fmt.Sprintf("%s[%d]", key, i), "empty tag")
+			}
+		}
+	}
+	for _, list := range []struct {
+		key  string
+		tags []string
+	}{{"tags.autoIfInRepoName", config.Tags.AutoIfInRepoName}, {"tags.static", config.Tags.Static}} {
+		for i, tag := range list.tags {
+			if strings.TrimSpace(tag) == "" {
+				checker.errorf(fmt.Sprintf("%s[%d]", list.key, i), "empty tag")
+			}
+		}
+	}
+	for _, from := range sortedKeys(config.Tags.Rename) {
+		if strings.TrimSpace(config.Tags.Rename[from]) == "" {
+			checker.errorf("tags.rename."+from, "renames %q to an empty tag", from)
+		}
+	}
+	for i, rule := range config.Tags.Rules {
+		key := fmt.Sprintf("tags.rules[%d]", i)
+		if len(rule.Add) == 0 && len(rule.Remove) == 0 {
+			checker.errorf(key, "adds and removes no tags")
+		}
+		if rule.NameRegex != "" {
+			checker.regexp(key+".nameRegex", rule.NameRegex)
+		}
+		for _, list := range []struct {
+			name  string
+			items []string
+		}{{"topics", rule.Topics}, {"files", rule.Files}, {"readmeKeywords", rule.ReadmeKeywords}, {"dependencies", rule.Dependencies}, {"add", rule.Add}, {"remove", rule.Remove}} {
+			for j, item := range list.items {
+				if strings.TrimSpace(item) == "" {
+					checker.errorf(fmt.Sprintf("%s.%s[%d]", key, list.name, j), "empty value")
+				}
+			}
+		}
+	}
+}
+
+func (checker *configChecker) checkBody(body BodyConfig) {
+	constructs := sortedKeys(defaultGFMShortcodes)
+	for i, transform := range body.GFMTransforms {
+		checker.oneOf(fmt.Sprintf("body.gfmTransforms[%d]", i), strings.TrimSpace(transform), constructs...)
+	}
+	for _, construct := range sortedKeys(body.GFMShortcodes) {
+		if !contains(constructs, construct) {
+			checker.errorf("body.gfmShortcodes."+construct, "unknown construct %q, expected one of %s", construct, strings.Join(constructs, ", "))
+		}
+	}
+	checker.notNegative("body.expandSourceLinksMaxBytes", body.ExpandSourceLinksMaxBytes)
+	checker.oneOf("body.tocMode", body.TOCMode, tocModeInject, tocModeFrontMatter)
+	checker.notNegative("body.readingWordsPerMinute", body.ReadingWordsPerMinute)
+
+	actions := []string{sanitizeActionAllow, sanitizeActionStrip, sanitizeActionEscape}
+	checker.oneOf("body.sanitizerPolicy.defaultAction", body.SanitizerPolicy.DefaultAction, actions...)
+	for _, tag := range sortedKeys(body.SanitizerPolicy.Tags) {
+		action := body.SanitizerPolicy.Tags[tag]
+		if action == "" {
+			checker.errorf("body.sanitizerPolicy.tags."+tag, "missing action, expected one of %s", strings.Join(actions, ", "))
+		}
+		checker.oneOf("body.sanitizerPolicy.tags."+tag, action, actions...)
+	}
+
+	for i, rule := range body.CleanupRules {
+		checker.oneOf(fmt.Sprintf("body.cleanupRules[%d]", i), strings.TrimSpace(rule), cleanupRuleBadges, cleanupRuleBoilerplate)
+	}
+	for i, pattern := range body.StripPatterns {
+		checker.regexp(fmt.Sprintf("body.stripPatterns[%d]", i), pattern)
+	}
+}
+
+// checkTemplates the rules and the default template, which have to name a built in template or one in the template directory
+func (checker *configChecker) checkTemplates(templates TemplatesConfig, templateDirectory string) {
+	t, err := loadTemplates(templateDirectory)
+	if err != nil {
+		checker.errorf("templates.directory", "%v", err)
+	}
+	exists := func(key string, name string) {
+		if t != nil && t.Lookup(name) == nil {
+			checker.errorf(key, "no template named %q", name)
+		}
+	}
+
+	if templates.Default != "" {
+		exists("templates.default", templates.Default)
+	}
+	for i, rule := range templates.Rules {
+		key := fmt.Sprintf("templates.rules[%d]", i)
+		if rule.Template == "" {
+			checker.errorf(key+".template", "missing template")
+		} else {
+			exists(key+".template", rule.Template)
+		}
+		if rule.NameRegex != "" {
+			checker.regexp(key+".nameRegex", rule.NameRegex)
+		}
+		if rule.ReadmeRegex != "" {
+			checker.regexp(key+".readmeRegex", rule.ReadmeRegex)
+		}
+		for j, feature := range rule.ReadmeFeatures {
+			checker.oneOf(fmt.Sprintf("%s.readmeFeatures[%d]", key, j), feature, readmeFeatureNames...)
+		}
+	}
+}
+
+func (checker *configChecker) checkOutputs(outputs OutputsConfig) {
+	targets := make([]string, 0, len(outputTargets))
+	for name := range outputTargets {
+		targets = append(targets, name)
+	}
+	sort.Strings(targets)
+	checker.oneOf("outputs.target", outputs.Target, targets...)
+	if outputTarget, ok := outputTargets[outputs.Target]; ok || outputs.Target == "" {
+		if !ok {
+			outputTarget = outputTargets[hugoTarget]
+		}
+		checker.oneOf("outputs.frontMatterFormat", outputs.FrontMatterFormat, outputTarget.FrontMatterFormats...)
+	}
+
+	if outputs.PostURLPattern != "" && !strings.Contains(outputs.PostURLPattern, "{slug}") && !strings.Contains(outputs.PostURLPattern, "{name}") {
+		checker.errorf("outputs.postURLPattern", "%q has no {slug} or {name} so every post gets the same URL", outputs.PostURLPattern)
+	}
+	checker.absoluteURL("outputs.siteBaseURL", outputs.SiteBaseURL)
+
+	for _, field := range sortedKeys(outputs.Search.FieldBoosts) {
+		key := "outputs.search.fieldBoosts." + field
+		if !containsFold(searchIndexFields, field) {
+			checker.errorf(key, "unknown field %q, expected one of %s", field, strings.Join(searchIndexFields, ", "))
+		} else if outputs.Search.FieldBoosts[field] < 0 {
+			checker.errorf(key, "must be 0 or more, got %v", outputs.Search.FieldBoosts[field])
+		}
+	}
+	checker.notNegative("outputs.search.excerptWords", outputs.Search.ExcerptWords)
+
+	for i, format := range outputs.Feeds.Formats {
+		checker.oneOf(fmt.Sprintf("outputs.feeds.formats[%d]", i), format, rssFeedFormat, atomFeedFormat, jsonFeedFormat)
+	}
+	if outputs.Feeds.ItemLimit != nil {
+		checker.notNegative("outputs.feeds.itemLimit", *outputs.Feeds.ItemLimit)
+	}
+	checker.oneOf("outputs.feeds.content", outputs.Feeds.Content, feedContentSummary, feedContentFull)
+	checker.oneOf("outputs.feeds.sort", outputs.Feeds.Sort, feedSortCreated, feedSortPushed)
+
+	socialCards := outputs.SocialCards
+	checker.oneOf("outputs.socialCards.mode", socialCards.Mode, socialCardsNextToPost, socialCardsStatic)
+	if socialCards.Mode == socialCardsStatic && socialCards.StaticDirectory == "" {
+		checker.errorf("outputs.socialCards.mode", "static needs outputs.socialCards.staticDirectory")
+	}
+	for _, colour := range []struct {
+		key   string
+		value string
+	}{
+		{"outputs.socialCards.layout.background", socialCards.Layout.Background},
+		{"outputs.socialCards.layout.foreground", socialCards.Layout.Foreground},
+		{"outputs.socialCards.layout.muted", socialCards.Layout.Muted},
+	} {
+		if _, err := parseHexColor(colour.value); err != nil {
+			checker.errorf(colour.key, "%v, expected #rrggbb", err)
+		}
+	}
+	for _, size := range []struct {
+		key   string
+		value float64
+	}{
+		{"outputs.socialCards.layout.width", float64(socialCards.Layout.Width)},
+		{"outputs.socialCards.layout.height", float64(socialCards.Layout.Height)},
+		{"outputs.socialCards.layout.titleSize", socialCards.Layout.TitleSize},
+		{"outputs.socialCards.layout.tagSize", socialCards.Layout.TagSize},
+		{"outputs.socialCards.layout.footerSize", socialCards.Layout.FooterSize},
+	} {
+		if size.value <= 0 {
+			checker.errorf(size.key, "must be more than 0, got %v", size.value)
+		}
+	}
+}
+
+// validateConfig every problem of config that can be found without fetching repos
+func validateConfig(config *Config, sources ConfigSources) []ConfigProblem {
+	checker := &configChecker{sources: sources, problems: make([]ConfigProblem, 0)}
+
+	if config.GitHub.AccessToken == "" {
+		checker.warnf("github.accessToken", "not set, GitHub allows 60 unauthenticated API requests an hour")
+	}
+	checker.absoluteURL("github.apiBaseURL", config.GitHub.APIBaseURL)
+
+	checker.checkFilters(config.Filters)
+	checker.checkTitlesAndTags(config)
+	checker.checkBody(config.Body)
+	checker.checkTemplates(config.Templates, templateDirectory)
+	checker.checkOutputs(config.Outputs)
+
+	checker.notNegative("linkCheck.concurrency", config.LinkCheck.Concurrency)
+	if config.LinkCheck.HostIntervalMS != nil {
+		checker.notNegative("linkCheck.hostIntervalMS", *config.LinkCheck.HostIntervalMS)
+	}
+	if config.LinkCheck.TimeoutMS != nil {
+		checker.notNegative("linkCheck.timeoutMS", *config.LinkCheck.TimeoutMS)
+	}
+	if config.LinkCheck.BrokenCacheTTLMinutes != nil {
+		checker.notNegative("linkCheck.brokenCacheTTLMinutes", *config.LinkCheck.BrokenCacheTTLMinutes)
+	}
+
+	for _, name := range sortedKeys(config.Profiles) {
+		if len(config.Profiles[name].Profiles) > 0 {
+			checker.errorf("profiles."+name+".profiles", "a profile can't have profiles of its own")
+		}
+	}
+	return checker.problems
+}
+
+func countConfigProblems(problems []ConfigProblem, severity string) int {
+	count := 0
+	for _, problem := range problems {
+		if problem.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+func writeConfigProblems(w io.Writer, problems []ConfigProblem, format string) error {
+	if format == "json" {
+		b, err := json.MarshalIndent(problems, "", "  ")
+		if err != nil {
+			log.Printf("json.MarshalIndent failed\n")
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintln(w, problem.String())
+	}
+	fmt.Fprintf(w, "%d errors, %d warnings\n", countConfigProblems(problems, configProblemError), countConfigProblems(problems, configProblemWarning))
+	return nil
+}
+
+// checkConfig loads the config once for the command, uses it for flags that weren't given and validates it.
+// every profile is only validated by validate-config, other commands get the problems of profiles they don't use as warnings
+func checkConfig() (*Config, []ConfigProblem) {
+	config, sources, problems := readConfig()
+	loadedConfig = config
+	applyConfigToFlags(config)
+	problems = append(problems, validateConfig(config, sources)...)
+	if profile == "" {
+		problems = append(problems, checkConfigProfiles(config, problems)...)
+	}
+	if command != "validate-config" {
+		for i, problem := range problems {
+			if isOtherProfileProblem(problem) {
+				problems[i].Severity = configProblemWarning
+			}
+		}
+	}
+	return config, problems
+}
+
+// isOtherProfileProblem whether problem is in a profile other than -profile, so it doesn't affect the command
+func isOtherProfileProblem(problem ConfigProblem) bool {
+	if problem.Profile != "" {
+		return problem.Profile != profile
+	}
+	if !strings.HasPrefix(problem.Key, "profiles.") {
+		return false
+	}
+	return profile == "" || !strings.HasPrefix(problem.Key, "profiles."+profile+".")
+}
+
+// checkConfigProfiles validates config with each of its profiles laid over it.
+// problems the config has without a profile are already reported and left out
+func checkConfigProfiles(config *Config, configProblems []ConfigProblem) []ConfigProblem {
+	reported := make(map[ConfigProblem]bool)
+	for _, problem := range configProblems {
+		reported[problem] = true
+	}
+
+	defer func(name string, directory string) {
+		profile, templateDirectory = name, directory
+	}(profile, templateDirectory)
+
+	problems := make([]ConfigProblem, 0)
+	for _, name := range sortedKeys(config.Profiles) {
+		profile = name
+		profileConfig, sources, profileProblems := readConfig()
+		if !givenFlags["template-dir"] {
+			templateDirectory = profileConfig.Templates.Directory
+		}
+		for _, problem := range append(profileProblems, validateConfig(profileConfig, sources)...) {
+			if reported[problem] {
+				continue
+			}
+			problem.Profile = name
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+const testInvalidConfigYAML = `filters:
+  include: [".*-playground", "(", ""]
+tags:
+  statik: [aws]
+  rename:
+    js: ""
+body:
+  readingWordsPerMinute: fast
+  tocMode: sidebar
+`
+
+func findConfigProblem(problems []ConfigProblem, key string) (ConfigProblem, bool) {
+	for _, problem := range problems {
+		if problem.Key == key {
+			return problem, true
+		}
+	}
+	return ConfigProblem{}, false
+}
+
+func TestCheckConfigPositions(t *testing.T) {
+	withTestConfig(t, testInvalidConfigYAML, "outputs.feeds.formats=[rss, atom2]")
+	os.Setenv("REPO_NAME_EXCLUDE_FILTERS", "ok,[")
+	defer os.Unsetenv("REPO_NAME_EXCLUDE_FILTERS")
+
+	_, problems := checkConfig()
+	for _, want := range []ConfigProblem{
+		{Severity: configProblemError, Key: "tags.statik", Position: configPath + ":4", Message: "unknown key statik"},
+		{Severity: configProblemError, Key: "body.readingWordsPerMinute", Position: configPath + ":8"},
+		{Severity: configProblemError, Key: "filters.include[1]", Position: configPath + ":2:30"},
+		{Severity: configProblemWarning, Key: "filters.include[2]", Position: configPath + ":2:35", Message: "empty filter matches every repo"},
+		{Severity: configProblemError, Key: "filters.exclude[1]", Position: "REPO_NAME_EXCLUDE_FILTERS item 2"},
+		{Severity: configProblemError, Key: "tags.rename.js", Position: configPath + ":6:9"},
+		{Severity: configProblemError, Key: "body.tocMode", Position: configPath + ":9:12"},
+		{Severity: configProblemError, Key: "outputs.feeds.formats[1]", Position: "-set outputs.feeds.formats"},
+	} {
+		problem, ok := findConfigProblem(problems, want.Key)
+		if !ok {
+			t.Errorf("expected a problem with %s in %v", want.Key, problems)
+			continue
+		}
+		if problem.Severity != want.Severity || problem.Position != want.Position || want.Message != "" && problem.Message != want.Message {
+			t.Errorf("expected %v, got %v", want, problem)
+		}
+	}
+}
+
+func TestCheckConfigSample(t *testing.T) {
+	b, err := ioutil.ReadFile("config.sample.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withTestConfig(t, string(b))
+	withTestCommand(t, "validate-config")
+	_, problems := checkConfig()
+	for _, problem := range problems {
+		if problem.Severity == configProblemError {
+			t.Errorf("unexpected problem %s", problem)
+		}
+	}
+}
+
+func TestValidateConfigWarnings(t *testing.T) {
+	config := defaultConfig()
+	config.Filters.Exclude = []string{".*"}
+	problems := validateConfig(config, newConfigSources())
+
+	if problem, ok := findConfigProblem(problems, "filters.include"); !ok || problem.Severity != configProblemWarning || problem.Position != "default" {
+		t.Errorf("expected a warning for no include filters, got %v", problems)
+	}
+	if problem, ok := findConfigProblem(problems, "filters.exclude[0]"); !ok || problem.Message != `".*" excludes every repo` {
+		t.Errorf("expected a warning for an exclude filter that matches every repo, got %v", problems)
+	}
+	if countConfigProblems(problems, configProblemError) != 0 {
+		t.Errorf("expected no errors, got %v", problems)
+	}
+}
+
+func TestValidateConfigTemplates(t *testing.T) {
+	config := defaultConfig()
+	config.Templates.Default = "missing.md"
+	config.Templates.Rules = []TemplateRule{{Template: postTemplateName, NameRegex: "*", ReadmeFeatures: []string{"videos"}}}
+	problems := validateConfig(config, newConfigSources())
+	for _, key := range []string{"templates.default", "templates.rules[0].nameRegex", "templates.rules[0].readmeFeatures[0]"} {
+		if problem, ok := findConfigProblem(problems, key); !ok || problem.Severity != configProblemError {
+			t.Errorf("expected an error for %s, got %v", key, problems)
+		}
+	}
+	if _, ok := findConfigProblem(problems, "templates.rules[0].template"); ok {
+		t.Errorf("expected %s to exist, got %v", postTemplateName, problems)
+	}
+}
+
+func TestWriteConfigProblems(t *testing.T) {
+	var buf bytes.Buffer
+	problems := []ConfigProblem{
+		{Severity: configProblemError, Key: "body.tocMode", Position: "config.yaml:9:12", Message: `unknown value "sidebar"`},
+		{Severity: configProblemWarning, Key: "filters.include", Position: "default", Message: "no include filters"},
+	}
+	if err := writeConfigProblems(&buf, problems, "text"); err != nil {
+		t.Fatal(err)
+	}
+	want := "config.yaml:9:12: error: body.tocMode: unknown value \"sidebar\"\ndefault: warning: filters.include: no include filters\n1 errors, 1 warnings\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestGetFilteredReposInvalidFilter(t *testing.T) {
+	os.Setenv("REPO_NAME_INCLUDE_FILTERS", ".*-playground,(")
+	defer os.Unsetenv("REPO_NAME_INCLUDE_FILTERS")
+	_, err := getFilteredRepos([]*github.Repository{newTestRepo("lambda-playground")})
+	if err == nil || !strings.Contains(err.Error(), "filters.include[1]") {
+		t.Errorf("expected an error naming filters.include[1], got %v", err)
+	}
+}
+
+func withTestCommand(t *testing.T, name string) {
+	previous := command
+	command = name
+	t.Cleanup(func() { command = previous })
+}
+
+func TestCheckConfigProfiles(t *testing.T) {
+	withTestConfig(t, "profiles:\n  team:\n    tags:\n      statik: [aws]\n    profiles:\n      nested: {}\n")
+	_, problems := checkConfig()
+	if problem, ok := findConfigProblem(problems, "profiles.team.tags.statik"); !ok || problem.Position != configPath+":4" {
+		t.Errorf("expected an unknown key in the profile, got %v", problems)
+	}
+	if _, ok := findConfigProblem(problems, "profiles.team.profiles"); !ok {
+		t.Errorf("expected an error for a nested profile, got %v", problems)
+	}
+}
+
+func TestValidateConfigTagRules(t *testing.T) {
+	config := defaultConfig()
+	config.Tags.Rules = []TagRule{{NameRegex: "("}, {Files: []string{""}, Add: []string{"docker"}}}
+	problems := validateConfig(config, newConfigSources())
+	for _, key := range []string{"tags.rules[0]", "tags.rules[0].nameRegex", "tags.rules[1].files[0]"} {
+		if problem, ok := findConfigProblem(problems, key); !ok || problem.Severity != configProblemError {
+			t.Errorf("expected an error for %s, got %v", key, problems)
+		}
+	}
+}
+
+func TestCheckConfigProfilesMerged(t *testing.T) {
+	withTestConfig(t, "filters:\n  include: [\".*-playground\"]\nprofiles:\n  team:\n    filters:\n      include: [\"(\"]\n  blog:\n    body:\n      tocMode: sidebar\n")
+	withTestCommand(t, "validate-config")
+	_, problems := checkConfig()
+	for _, want := range []ConfigProblem{
+		{Severity: configProblemError, Key: "filters.include[0]", Position: configPath + ":6:17", Profile: "team"},
+		{Severity: configProblemError, Key: "body.tocMode", Position: configPath + ":9:16", Profile: "blog"},
+	} {
+		problem, ok := findConfigProblem(problems, want.Key)
+		if !ok || problem.Severity != want.Severity || problem.Position != want.Position || problem.Profile != want.Profile {
+			t.Errorf("expected %v, got %v", want, problems)
+		}
+	}
+	if profile != "" {
+		t.Errorf("expected the profile to be restored, got %q", profile)
+	}
+	if !strings.Contains(problems[len(problems)-1].String(), ": error: profile team: filters.include[0]: ") {
+		t.Errorf("expected the profile in %q", problems[len(problems)-1].String())
+	}
+}
+
+func TestCheckConfigOtherProfilesWarn(t *testing.T) {
+	withTestConfig(t, "profiles:\n  team:\n    filters:\n      include: [\"(\"]\n    tags:\n      statik: [aws]\n  blog:\n    body:\n      tocMode: sidebar\n")
+	withTestCommand(t, "generate-markdown-post-files")
+	_, problems := checkConfig()
+	for _, key := range []string{"filters.include[0]", "profiles.team.tags.statik", "body.tocMode"} {
+		if problem, ok := findConfigProblem(problems, key); !ok || problem.Severity != configProblemWarning {
+			t.Errorf("expected a warning for %s, got %v", key, problems)
+		}
+	}
+
+	defer func(name string) { profile = name }(profile)
+	profile = "team"
+	_, problems = checkConfig()
+	for _, key := range []string{"filters.include[0]", "profiles.team.tags.statik"} {
+		if problem, ok := findConfigProblem(problems, key); !ok || problem.Severity != configProblemError {
+			t.Errorf("expected an error for %s with its profile, got %v", key, problems)
+		}
+	}
+}
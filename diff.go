@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffHunk lines a[AStart:AEnd] are replaced by b[BStart:BEnd]
+type DiffHunk struct {
+	AStart, AEnd int
+	BStart, BEnd int
+}
+
+// MergeConflict a range of base lines both sides changed differently
+type MergeConflict struct {
+	BaseStart, BaseEnd int
+}
+
+func (c MergeConflict) String() string {
+	return fmt.Sprintf("lines %d-%d", c.BaseStart+1, c.BaseEnd)
+}
+
+const conflictMarkerOurs = "<<<<<<< on disk\n"
+const conflictMarkerBase = "||||||| last generated\n"
+const conflictMarkerSeparator = "=======\n"
+const conflictMarkerTheirs = ">>>>>>> generated\n"
+
+// splitLines splits text after every newline so joining the lines gives back text
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines the hunks that turn a into b from a longest common subsequence of lines
+func diffLines(a, b []string) []DiffHunk {
+	// lcs[i][j] length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	hunks := make([]DiffHunk, 0)
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		if i < len(a) && j < len(b) && a[i] == b[j] {
+			i++
+			j++
+			continue
+		}
+		hunk := DiffHunk{AStart: i, BStart: j}
+		for i < len(a) || j < len(b) {
+			if i < len(a) && j < len(b) && a[i] == b[j] {
+				break
+			}
+			if j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]) {
+				i++
+			} else {
+				j++
+			}
+		}
+		hunk.AEnd, hunk.BEnd = i, j
+		hunks = append(hunks, hunk)
+	}
+	return hunks
+}
+
+// applyHunks the lines of side over base[start:end]. hunks must fall within that range
+func applyHunks(base []string, side []string, hunks []DiffHunk, start, end int) []string {
+	lines := make([]string, 0)
+	position := start
+	for _, hunk := range hunks {
+		lines = append(lines, base[position:hunk.AStart]...)
+		lines = append(lines, side[hunk.BStart:hunk.BEnd]...)
+		position = hunk.AEnd
+	}
+	return append(lines, base[position:end]...)
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeLines three way merges the changes ours and theirs made to base.
+// changes to the same base lines conflict unless they are identical.
+// conflicts are written with git style markers
+func mergeLines(base, ours, theirs []string) ([]string, []MergeConflict) {
+	type sideHunk struct {
+		DiffHunk
+		ours bool
+	}
+
+	oursHunks := diffLines(base, ours)
+	theirsHunks := diffLines(base, theirs)
+	hunks := make([]sideHunk, 0, len(oursHunks)+len(theirsHunks))
+	i, j := 0, 0
+	for i < len(oursHunks) || j < len(theirsHunks) {
+		if j == len(theirsHunks) || (i < len(oursHunks) && oursHunks[i].AStart <= theirsHunks[j].AStart) {
+			hunks = append(hunks, sideHunk{oursHunks[i], true})
+			i++
+		} else {
+			hunks = append(hunks, sideHunk{theirsHunks[j], false})
+			j++
+		}
+	}
+
+	merged := make([]string, 0, len(base))
+	conflicts := make([]MergeConflict, 0)
+	position := 0
+	for k := 0; k < len(hunks); {
+		// group hunks whose base ranges overlap. insertions at the same line are grouped too
+		start, end := hunks[k].AStart, hunks[k].AEnd
+		groupOurs, groupTheirs := make([]DiffHunk, 0), make([]DiffHunk, 0)
+		for ; k < len(hunks) && (hunks[k].AStart < end || hunks[k].AStart == start && start == end); k++ {
+			if hunks[k].AEnd > end {
+				end = hunks[k].AEnd
+			}
+			if hunks[k].ours {
+				groupOurs = append(groupOurs, hunks[k].DiffHunk)
+			} else {
+				groupTheirs = append(groupTheirs, hunks[k].DiffHunk)
+			}
+		}
+
+		merged = append(merged, base[position:start]...)
+		position = end
+
+		oursLines := applyHunks(base, ours, groupOurs, start, end)
+		theirsLines := applyHunks(base, theirs, groupTheirs, start, end)
+		switch {
+		case len(groupTheirs) == 0:
+			merged = append(merged, oursLines...)
+		case len(groupOurs) == 0 || equalLines(oursLines, theirsLines):
+			merged = append(merged, theirsLines...)
+		default:
+			conflicts = append(conflicts, MergeConflict{BaseStart: start, BaseEnd: end})
+			merged = append(merged, conflictMarkerOurs)
+			merged = append(merged, terminateLines(oursLines)...)
+			merged = append(merged, conflictMarkerBase)
+			merged = append(merged, terminateLines(base[start:end])...)
+			merged = append(merged, conflictMarkerSeparator)
+			merged = append(merged, terminateLines(theirsLines)...)
+			merged = append(merged, conflictMarkerTheirs)
+		}
+	}
+	merged = append(merged, base[position:]...)
+	return merged, conflicts
+}
+
+// terminateLines makes sure the last line ends with a newline so a conflict marker can follow it
+func terminateLines(lines []string) []string {
+	if len(lines) == 0 || strings.HasSuffix(lines[len(lines)-1], "\n") {
+		return lines
+	}
+	terminated := append([]string{}, lines...)
+	terminated[len(terminated)-1] += "\n"
+	return terminated
+}
+
+// unifiedDiff a and b in unified diff format with contextLines of context around each change
+func unifiedDiff(aName, bName string, a, b string, contextLines int) string {
+	aLines, bLines := splitLines(a), splitLines(b)
+	hunks := diffLines(aLines, bLines)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", aName, bName)
+
+	writeLine := func(prefix string, line string) {
+		sb.WriteString(prefix + line)
+		if !strings.HasSuffix(line, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+	rangeHeader := func(start, length int) string {
+		if length == 0 {
+			return fmt.Sprintf("%d,0", start)
+		}
+		return fmt.Sprintf("%d,%d", start+1, length)
+	}
+
+	for first := 0; first < len(hunks); {
+		last := first
+		for last+1 < len(hunks) && hunks[last+1].AStart-hunks[last].AEnd <= 2*contextLines {
+			last++
+		}
+
+		aStart := hunks[first].AStart - contextLines
+		if aStart < 0 {
+			aStart = 0
+		}
+		aEnd := hunks[last].AEnd + contextLines
+		if aEnd > len(aLines) {
+			aEnd = len(aLines)
+		}
+		bStart := hunks[first].BStart - (hunks[first].AStart - aStart)
+		bEnd := hunks[last].BEnd + (aEnd - hunks[last].AEnd)
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", rangeHeader(aStart, aEnd-aStart), rangeHeader(bStart, bEnd-bStart))
+
+		position := aStart
+		for _, hunk := range hunks[first : last+1] {
+			for _, line := range aLines[position:hunk.AStart] {
+				writeLine(" ", line)
+			}
+			for _, line := range aLines[hunk.AStart:hunk.AEnd] {
+				writeLine("-", line)
+			}
+			for _, line := range bLines[hunk.BStart:hunk.BEnd] {
+				writeLine("+", line)
+			}
+			position = hunk.AEnd
+		}
+		for _, line := range aLines[position:aEnd] {
+			writeLine(" ", line)
+		}
+		first = last + 1
+	}
+	return sb.String()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	a := splitLines("a\nb\nc\nd\n")
+	b := splitLines("a\nx\nc\nd\ne\n")
+	hunks := diffLines(a, b)
+	want := []DiffHunk{{AStart: 1, AEnd: 2, BStart: 1, BEnd: 2}, {AStart: 4, AEnd: 4, BStart: 4, BEnd: 5}}
+	if len(hunks) != len(want) {
+		t.Fatalf("expected %v, got %v", want, hunks)
+	}
+	for i := range want {
+		if hunks[i] != want[i] {
+			t.Errorf("expected %v, got %v", want[i], hunks[i])
+		}
+	}
+}
+
+func TestMergeLines(t *testing.T) {
+	tests := []struct {
+		name, base, ours, theirs, want string
+		conflicts                      int
+	}{
+		{"ours only", "a\nb\nc\n", "a\nB\nc\n", "a\nb\nc\n", "a\nB\nc\n", 0},
+		{"theirs only", "a\nb\nc\n", "a\nb\nc\n", "a\nb\nc\nd\n", "a\nb\nc\nd\n", 0},
+		{"both separate", "a\nb\nc\nd\ne\n", "A\nb\nc\nd\ne\n", "a\nb\nc\nd\nE\n", "A\nb\nc\nd\nE\n", 0},
+		{"both same", "a\nb\nc\n", "a\nX\nc\n", "a\nX\nc\n", "a\nX\nc\n", 0},
+		{"conflict", "a\nb\nc\n", "a\nours\nc\n", "a\ntheirs\nc\n",
+			"a\n" + conflictMarkerOurs + "ours\n" + conflictMarkerBase + "b\n" + conflictMarkerSeparator + "theirs\n" + conflictMarkerTheirs + "c\n", 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			merged, conflicts := mergeLines(splitLines(test.base), splitLines(test.ours), splitLines(test.theirs))
+			if got := strings.Join(merged, ""); got != test.want {
+				t.Errorf("expected %q, got %q", test.want, got)
+			}
+			if len(conflicts) != test.conflicts {
+				t.Errorf("expected %d conflicts, got %v", test.conflicts, conflicts)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const dryRunNew = "new"
+const dryRunChanged = "changed"
+const dryRunUnchanged = "unchanged"
+const dryRunOrphaned = "orphaned"
+
+const diffContextLines = 3
+
+// DryRunFile what generating would do to a post file
+type DryRunFile struct {
+	PostFileName string `json:"postFileName"`
+	Repo         string `json:"repo"`
+	Status       string `json:"status"`
+	Edited       bool   `json:"edited,omitempty"`
+	Diff         string `json:"diff,omitempty"`
+}
+
+// DryRunReport what generating would do to the destination directory
+type DryRunReport struct {
+	New       int          `json:"new"`
+	Changed   int          `json:"changed"`
+	Unchanged int          `json:"unchanged"`
+	Orphaned  int          `json:"orphaned"`
+	Files     []DryRunFile `json:"files"`
+}
+
+// getDryRunReport compares the posts to the destination directory without writing anything.
+// with showDiff every changed file has a unified diff from the file on disk to the generated post
+func getDryRunReport(repoPosts []RepoPost, destinationDirectory string, showDiff bool) (DryRunReport, error) {
+	report := DryRunReport{Files: make([]DryRunFile, 0)}
+
+	state, err := loadGeneratedPostsState(destinationDirectory)
+	if err != nil {
+		log.Printf("loadGeneratedPostsState(%s) failed\n", destinationDirectory)
+		return report, err
+	}
+
+	for _, repoPost := range repoPosts {
+		file := DryRunFile{PostFileName: filepath.ToSlash(repoPost.PostFileName), Repo: repoPost.Repo.GetFullName()}
+		b, err := ioutil.ReadFile(filepath.Join(destinationDirectory, repoPost.PostFileName))
+		switch {
+		case os.IsNotExist(err):
+			file.Status = dryRunNew
+			report.New++
+			if showDiff {
+				file.Diff = unifiedDiff("/dev/null", "b/"+file.PostFileName, "", repoPost.PostFileContents, diffContextLines)
+			}
+		case err != nil:
+			return report, err
+		case string(b) == repoPost.PostFileContents:
+			file.Status = dryRunUnchanged
+			report.Unchanged++
+		default:
+			file.Status = dryRunChanged
+			report.Changed++
+			file.Edited = state.isEdited(repoPost, string(b))
+			if showDiff {
+				file.Diff = unifiedDiff("a/"+file.PostFileName, "b/"+file.PostFileName, string(b), repoPost.PostFileContents, diffContextLines)
+			}
+		}
+		report.Files = append(report.Files, file)
+	}
+
+	for _, name := range getOrphanedPostFiles(state, repoPosts, destinationDirectory) {
+		report.Orphaned++
+		report.Files = append(report.Files, DryRunFile{PostFileName: name, Repo: state.Files[name].Repo, Status: dryRunOrphaned})
+	}
+	return report, nil
+}
+
+func writeDryRunReport(w io.Writer, report DryRunReport, format string) error {
+	if format == "json" {
+		b, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Printf("json.MarshalIndent failed\n")
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+
+	for _, file := range report.Files {
+		if file.Status == dryRunUnchanged {
+			continue
+		}
+		edited := ""
+		if file.Edited {
+			edited = " (edited, -on-edited=" + onEdited + ")"
+		}
+		fmt.Fprintf(w, "%s %s%s\n", file.Status, file.PostFileName, edited)
+		if file.Diff != "" {
+			fmt.Fprint(w, file.Diff)
+		}
+	}
+	fmt.Fprintf(w, "%d new, %d changed, %d unchanged, %d orphaned\n", report.New, report.Changed, report.Unchanged, report.Orphaned)
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGetDryRunReport(t *testing.T) {
+	destinationDirectory, err := ioutil.TempDir("", "posts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(destinationDirectory)
+
+	state := &GeneratedPostsState{Files: make(map[string]GeneratedFile)}
+	for _, name := range []string{"unchanged-playground", "changed-playground", "orphaned-playground"} {
+		repoPost := RepoPost{Repo: newTestRepo(name), PostFileName: "generated-" + name + ".md", PostFileContents: "one\ntwo\nthree\n"}
+		if _, err := writeGeneratedPostFile(repoPost, destinationDirectory, state, onEditedSkip); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := saveGeneratedPostsState(destinationDirectory, state); err != nil {
+		t.Fatal(err)
+	}
+
+	repoPosts := []RepoPost{
+		{Repo: newTestRepo("unchanged-playground"), PostFileName: "generated-unchanged-playground.md", PostFileContents: "one\ntwo\nthree\n"},
+		{Repo: newTestRepo("changed-playground"), PostFileName: "generated-changed-playground.md", PostFileContents: "one\n2\nthree\n"},
+		{Repo: newTestRepo("new-playground"), PostFileName: "generated-new-playground.md", PostFileContents: "new\n"},
+	}
+	report, err := getDryRunReport(repoPosts, destinationDirectory, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.New != 1 || report.Changed != 1 || report.Unchanged != 1 || report.Orphaned != 1 {
+		t.Errorf("unexpected summary %+v", report)
+	}
+	wantDiff := "--- a/generated-changed-playground.md\n+++ b/generated-changed-playground.md\n@@ -1,3 +1,3 @@\n one\n-two\n+2\n three\n"
+	if report.Files[1].Diff != wantDiff {
+		t.Errorf("expected diff %q, got %q", wantDiff, report.Files[1].Diff)
+	}
+	if fileExists(filepath.Join(destinationDirectory, "generated-new-playground.md")) {
+		t.Error("expected dry run not to write files")
+	}
+
+	var buf bytes.Buffer
+	if err := writeDryRunReport(&buf, report, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var decoded DryRunReport
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded.Files) != 4 || decoded.Files[3].Status != dryRunOrphaned {
+		t.Errorf("unexpected json report %s %v", buf.String(), err)
+	}
+
+	buf.Reset()
+	writeDryRunReport(&buf, report, "text")
+	if !strings.HasSuffix(buf.String(), "1 new, 1 changed, 1 unchanged, 1 orphaned\n") || strings.Contains(buf.String(), "unchanged generated") {
+		t.Errorf("unexpected text report %s", buf.String())
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11"
+	want := "--- a\n+++ b\n@@ -1,6 +1,6 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n@@ -8,3 +8,4 @@\n 8\n 9\n 10\n+11\n\\ No newline at end of file\n"
+	if got := unifiedDiff("a", "b", a, b, 3); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if got := unifiedDiff("a", "b", a, a, 3); got != "" {
+		t.Errorf("expected no diff, got %q", got)
+	}
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/github"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/html"
+)
+
+const rssFeedFormat = "rss"
+const atomFeedFormat = "atom"
+const jsonFeedFormat = "json"
+
+const feedContentSummary = "summary"
+const feedContentFull = "full"
+
+const feedSortCreated = "created"
+const feedSortPushed = "pushed"
+
+const defaultFeedItemLimit = 20
+
+var feedFileNames = map[string]string{
+	rssFeedFormat:  "feed.xml",
+	atomFeedFormat: "atom.xml",
+	jsonFeedFormat: "feed.json",
+}
+
+// FeedOptions what goes in the feeds and where they link to
+type FeedOptions struct {
+	Title     string
+	BaseURL   string
+	Formats   []string
+	ItemLimit int
+	Content   string
+	SortBy    string
+}
+
+// FeedItem a post as it appears in every feed format
+type FeedItem struct {
+	ID        string
+	Title     string
+	URL       string
+	Content   string
+	HTML      bool
+	Tags      []string
+	Author    Author
+	Published time.Time
+	Updated   time.Time
+}
+
+// RSS rss 2.0 document
+type RSS struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel RSSChannel `xml:"channel"`
+}
+
+// RSSChannel rss 2.0 channel
+type RSSChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []RSSItem `xml:"item"`
+}
+
+// RSSItem rss 2.0 item
+type RSSItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        string   `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Categories  []string `xml:"category"`
+	Description string   `xml:"description"`
+}
+
+// AtomFeed atom 1.0 feed
+type AtomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []AtomLink  `xml:"link"`
+	Author  *AtomAuthor `xml:"author,omitempty"`
+	Entries []AtomEntry `xml:"entry"`
+}
+
+// AtomLink atom link
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+// AtomAuthor atom author
+type AtomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+// AtomCategory atom category
+type AtomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// AtomText atom text construct
+type AtomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// AtomEntry atom entry
+type AtomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Links      []AtomLink     `xml:"link"`
+	Author     *AtomAuthor    `xml:"author,omitempty"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Categories []AtomCategory `xml:"category"`
+	Summary    *AtomText      `xml:"summary,omitempty"`
+	Content    *AtomText      `xml:"content,omitempty"`
+}
+
+// JSONFeed json feed 1.1 document
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Authors     []JSONFeedName `json:"authors,omitempty"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+// JSONFeedName json feed author
+type JSONFeedName struct {
+	Name   string `json:"name"`
+	URL    string `json:"url,omitempty"`
+	Avatar string `json:"avatar,omitempty"`
+}
+
+// JSONFeedItem json feed item
+type JSONFeedItem struct {
+	ID            string         `json:"id"`
+	URL           string         `json:"url"`
+	Title         string         `json:"title"`
+	ContentHTML   string         `json:"content_html,omitempty"`
+	Summary       string         `json:"summary,omitempty"`
+	DatePublished string         `json:"date_published"`
+	DateModified  string         `json:"date_modified"`
+	Authors       []JSONFeedName `json:"authors,omitempty"`
+	Tags          []string       `json:"tags"`
+}
+
+func getFeedOptions() (FeedOptions, error) {
+	config := getConfig()
+	options := FeedOptions{
+		Title:     config.Outputs.Feeds.Title,
+		BaseURL:   strings.TrimSuffix(config.Outputs.SiteBaseURL, "/"),
+		Formats:   config.Outputs.Feeds.Formats,
+		ItemLimit: defaultFeedItemLimit,
+		Content:   config.Outputs.Feeds.Content,
+		SortBy:    config.Outputs.Feeds.Sort,
+	}
+
+	if options.BaseURL == "" {
+		return options, fmt.Errorf("outputs.siteBaseURL (SITE_BASE_URL) is required for absolute feed URLs")
+	}
+	if options.Title == "" {
+		options.Title = getHTMLSiteTitle()
+	}
+	if len(options.Formats) == 0 {
+		options.Formats = []string{rssFeedFormat, atomFeedFormat, jsonFeedFormat}
+	}
+	for _, format := range options.Formats {
+		if _, ok := feedFileNames[format]; !ok {
+			return options, fmt.Errorf("outputs.feeds.formats: unknown feed format %q", format)
+		}
+	}
+	if config.Outputs.Feeds.ItemLimit != nil {
+		options.ItemLimit = *config.Outputs.Feeds.ItemLimit
+	}
+	if options.Content == "" {
+		options.Content = feedContentSummary
+	}
+	if options.Content != feedContentSummary && options.Content != feedContentFull {
+		return options, fmt.Errorf("outputs.feeds.content: unknown content mode %q", options.Content)
+	}
+	if options.SortBy == "" {
+		options.SortBy = feedSortCreated
+	}
+	if options.SortBy != feedSortCreated && options.SortBy != feedSortPushed {
+		return options, fmt.Errorf("outputs.feeds.sort: unknown sort %q", options.SortBy)
+	}
+	return options, nil
+}
+
+func getFeedSortDate(repoPost *RepoPost, sortBy string) time.Time {
+	if sortBy == feedSortPushed && repoPost.Repo.PushedAt != nil {
+		return repoPost.Repo.PushedAt.Time
+	}
+	return repoPost.Repo.GetCreatedAt().Time
+}
+
+// getAbsoluteFeedHTML points relative links and images in the HTML of a post at the repo, since feed readers have no page to resolve them against.
+// images get the raw file, links the file on github and #anchors the post
+func getAbsoluteFeedHTML(repo *github.Repository, content string, postURL string) string {
+	var result strings.Builder
+	z := html.NewTokenizer(strings.NewReader(content))
+	for {
+		tokenType := z.Next()
+		if tokenType == html.ErrorToken {
+			break
+		}
+		raw := string(z.Raw())
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			result.WriteString(raw)
+			continue
+		}
+
+		token := z.Token()
+		rewritten := false
+		for i, attribute := range token.Attr {
+			switch {
+			case (attribute.Key == "href" || attribute.Key == "src") && strings.HasPrefix(attribute.Val, "#"):
+				token.Attr[i].Val = postURL + attribute.Val
+			case attribute.Key == "src" && isRelativeURL(attribute.Val):
+				token.Attr[i].Val = getRawFileURL(repo, strings.TrimPrefix(attribute.Val, "./"))
+			case attribute.Key == "href" && isRelativeURL(attribute.Val):
+				token.Attr[i].Val = getFileHTMLURL(repo, strings.TrimPrefix(attribute.Val, "./"))
+			default:
+				continue
+			}
+			rewritten = true
+		}
+		if rewritten {
+			result.WriteString(token.String())
+		} else {
+			result.WriteString(raw)
+		}
+	}
+	return result.String()
+}
+
+// getFeedItems the newest posts first, at most options.ItemLimit of them when it is positive
+func getFeedItems(repoPosts []RepoPost, outputTarget OutputTarget, options FeedOptions) ([]FeedItem, error) {
+	sorted := append([]RepoPost{}, repoPosts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return getFeedSortDate(&sorted[i], options.SortBy).After(getFeedSortDate(&sorted[j], options.SortBy))
+	})
+	if options.ItemLimit > 0 && len(sorted) > options.ItemLimit {
+		sorted = sorted[:options.ItemLimit]
+	}
+
+	items := make([]FeedItem, 0, len(sorted))
+	for i := range sorted {
+		repoPost := &sorted[i]
+		url := options.BaseURL + getPostURLPath(repoPost, outputTarget)
+		item := FeedItem{
+			ID:        url,
+			Title:     repoPost.Title,
+			URL:       url,
+			Content:   repoPost.Summary,
+			Tags:      repoPost.Tags,
+			Author:    repoPost.Author,
+			Published: repoPost.Repo.GetCreatedAt().Time,
+			Updated:   getFeedSortDate(repoPost, feedSortPushed),
+		}
+		if item.Tags == nil {
+			item.Tags = make([]string, 0)
+		}
+		if options.Content == feedContentFull {
+			content, err := renderMarkdownToHTML(repoPost.Repo.GetName(), repoPost.FeedMarkdownBody)
+			if err != nil {
+				log.Printf("renderMarkdownToHTML(%s) failed\n", repoPost.Repo.GetName())
+				return nil, err
+			}
+			item.Content = getAbsoluteFeedHTML(repoPost.Repo, string(content), url)
+			item.HTML = true
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func getFeedUpdated(items []FeedItem) time.Time {
+	updated := time.Time{}
+	for _, item := range items {
+		if item.Updated.After(updated) {
+			updated = item.Updated
+		}
+	}
+	return updated
+}
+
+func marshalRSSFeed(items []FeedItem, options FeedOptions) ([]byte, error) {
+	rss := RSS{Version: "2.0", Channel: RSSChannel{Title: options.Title, Link: options.BaseURL + "/", Description: options.Title}}
+	if len(items) > 0 {
+		rss.Channel.LastBuildDate = getFeedUpdated(items).Format(time.RFC1123Z)
+	}
+	for _, item := range items {
+		rss.Channel.Items = append(rss.Channel.Items, RSSItem{
+			Title:       item.Title,
+			Link:        item.URL,
+			GUID:        item.ID,
+			PubDate:     item.Published.Format(time.RFC1123Z),
+			Categories:  item.Tags,
+			Description: item.Content,
+		})
+	}
+	b, err := xml.MarshalIndent(rss, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(b, '\n')...), nil
+}
+
+func marshalAtomFeed(items []FeedItem, options FeedOptions) ([]byte, error) {
+	feed := AtomFeed{
+		Title:   options.Title,
+		ID:      options.BaseURL + "/",
+		Updated: getFeedUpdated(items).Format(time.RFC3339),
+		Links:   []AtomLink{{Href: options.BaseURL + "/"}, {Href: options.BaseURL + "/" + feedFileNames[atomFeedFormat], Rel: "self"}},
+	}
+	for _, item := range items {
+		entry := AtomEntry{
+			Title:     item.Title,
+			ID:        item.ID,
+			Links:     []AtomLink{{Href: item.URL, Rel: "alternate"}},
+			Author:    &AtomAuthor{Name: item.Author.Name, URI: item.Author.URL},
+			Published: item.Published.Format(time.RFC3339),
+			Updated:   item.Updated.Format(time.RFC3339),
+		}
+		for _, tag := range item.Tags {
+			entry.Categories = append(entry.Categories, AtomCategory{Term: tag})
+		}
+		if item.HTML {
+			entry.Content = &AtomText{Type: "html", Body: item.Content}
+		} else {
+			entry.Summary = &AtomText{Type: "text", Body: item.Content}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	b, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(b, '\n')...), nil
+}
+
+func marshalJSONFeed(items []FeedItem, options FeedOptions) ([]byte, error) {
+	feed := JSONFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       options.Title,
+		HomePageURL: options.BaseURL + "/",
+		FeedURL:     options.BaseURL + "/" + feedFileNames[jsonFeedFormat],
+		Items:       make([]JSONFeedItem, 0, len(items)),
+	}
+	for _, item := range items {
+		jsonFeedItem := JSONFeedItem{
+			ID:            item.ID,
+			URL:           item.URL,
+			Title:         item.Title,
+			DatePublished: item.Published.Format(time.RFC3339),
+			DateModified:  item.Updated.Format(time.RFC3339),
+			Authors:       []JSONFeedName{{Name: item.Author.Name, URL: item.Author.URL, Avatar: item.Author.AvatarURL}},
+			Tags:          item.Tags,
+		}
+		if item.HTML {
+			jsonFeedItem.ContentHTML = item.Content
+		} else {
+			jsonFeedItem.Summary = item.Content
+		}
+		feed.Items = append(feed.Items, jsonFeedItem)
+	}
+	b, err := json.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// createFeeds writes feed.xml, atom.xml and feed.json for the configured formats into siteDirectory
+func createFeeds(repoPosts []RepoPost, siteDirectory string, options FeedOptions) error {
+	outputTarget, err := getOutputTarget()
+	if err != nil {
+		return err
+	}
+	items, err := getFeedItems(repoPosts, outputTarget, options)
+	if err != nil {
+		return err
+	}
+
+	marshalers := map[string]func([]FeedItem, FeedOptions) ([]byte, error){
+		rssFeedFormat:  marshalRSSFeed,
+		atomFeedFormat: marshalAtomFeed,
+		jsonFeedFormat: marshalJSONFeed,
+	}
+	for _, format := range options.Formats {
+		b, err := marshalers[format](items, options)
+		if err != nil {
+			log.Printf("marshal %s feed failed\n", format)
+			return err
+		}
+		if err := writeSiteFile(siteDirectory, feedFileNames[format], b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createFeedsForUser(username string, siteDirectory string) error {
+	options, err := getFeedOptions()
+	if err != nil {
+		log.Printf("getFeedOptions() failed\n")
+		return err
+	}
+	repoPosts, err := getRepoPosts(username)
+	if err != nil {
+		log.Printf("getRepoPosts(%s) failed\n", username)
+		return err
+	}
+	return createFeeds(repoPosts, siteDirectory, options)
+}
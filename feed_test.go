@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+func TestCreateFeeds(t *testing.T) {
+	siteDirectory, err := ioutil.TempDir("", "feeds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(siteDirectory)
+
+	repoPosts := []RepoPost{newTestRepoPost("older-playground", "older-playground", "some **bold** text\n", "aws"),
+		newTestRepoPost("newer-playground", "newer-playground", "some **bold** text\n", "aws"),
+		newTestRepoPost("pushed-playground", "pushed-playground", "some **bold** text\n", "aws")}
+	for i, repoPost := range repoPosts {
+		repoPost.Repo.CreatedAt = &github.Timestamp{Time: time.Date(2019, time.Month(1+i), 1, 0, 0, 0, 0, time.UTC)}
+		repoPost.Repo.PushedAt = &github.Timestamp{Time: time.Date(2020, time.Month(3-i), 1, 0, 0, 0, 0, time.UTC)}
+	}
+	options := FeedOptions{Title: "Projects", BaseURL: "https://example.com", Formats: []string{rssFeedFormat, atomFeedFormat, jsonFeedFormat},
+		ItemLimit: 2, Content: feedContentSummary, SortBy: feedSortCreated}
+	if err := createFeeds(repoPosts, siteDirectory, options); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(name string) []byte {
+		b, err := ioutil.ReadFile(filepath.Join(siteDirectory, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	var rss RSS
+	if err := xml.Unmarshal(read("feed.xml"), &rss); err != nil {
+		t.Fatal(err)
+	}
+	if len(rss.Channel.Items) != 2 || rss.Channel.Items[0].Link != "https://example.com/post/pushed-playground/" || rss.Channel.Items[0].Description != "learn pushed-playground" {
+		t.Errorf("unexpected rss items %+v", rss.Channel.Items)
+	}
+
+	var atom AtomFeed
+	if err := xml.Unmarshal(read("atom.xml"), &atom); err != nil {
+		t.Fatal(err)
+	}
+	if len(atom.Entries) != 2 || atom.Entries[1].Title != "newer-playground" || atom.Updated != "2020-02-01T00:00:00Z" {
+		t.Errorf("unexpected atom feed %+v", atom)
+	}
+
+	var jsonFeed JSONFeed
+	if err := json.Unmarshal(read("feed.json"), &jsonFeed); err != nil {
+		t.Fatal(err)
+	}
+	if len(jsonFeed.Items) != 2 || jsonFeed.FeedURL != "https://example.com/feed.json" || jsonFeed.Items[0].Summary == "" {
+		t.Errorf("unexpected json feed %+v", jsonFeed)
+	}
+}
+
+func TestGetFeedItemsFullContentSortedByPushed(t *testing.T) {
+	repoPosts := []RepoPost{newTestRepoPost("older-playground", "older-playground", "some **bold** text\n", "aws"),
+		newTestRepoPost("newer-playground", "newer-playground", "some **bold** text\n", "aws"),
+		newTestRepoPost("pushed-playground", "pushed-playground", "some **bold** text\n", "aws")}
+	for i, repoPost := range repoPosts {
+		repoPost.Repo.CreatedAt = &github.Timestamp{Time: time.Date(2019, time.Month(1+i), 1, 0, 0, 0, 0, time.UTC)}
+		repoPost.Repo.PushedAt = &github.Timestamp{Time: time.Date(2020, time.Month(3-i), 1, 0, 0, 0, 0, time.UTC)}
+	}
+	options := FeedOptions{BaseURL: "https://example.com", Content: feedContentFull, SortBy: feedSortPushed}
+	items, err := getFeedItems(repoPosts, outputTargets[jekyllTarget], options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 || items[0].Title != "older-playground" {
+		t.Fatalf("expected most recently pushed first, got %+v", items)
+	}
+	if !items[0].HTML || !strings.Contains(items[0].Content, "<strong>bold</strong>") || items[0].URL != "https://example.com/older-playground/" {
+		t.Errorf("unexpected item %+v", items[0])
+	}
+}
+
+func TestGetFeedItemsFullContentHTML(t *testing.T) {
+	repoPost := newTestRepoPost("feed-playground", "feed-playground", "", "aws")
+	repoPost.MarkdownBody = "{{< alert type=\"note\" >}}\nhi\n{{< /alert >}}\n"
+	repoPost.FeedMarkdownBody = "<div class=\"alert alert-note\" role=\"alert\">\n\nhi\n\n</div>\n\n" +
+		"<p align=\"center\"><img src=\"images/logo.png\"></p>\n\n![d](images/d.png) [docs](docs/README.md) [usage](#usage) [site](https://example.org)\n"
+	items, err := getFeedItems([]RepoPost{repoPost}, outputTargets[hugoTarget], FeedOptions{BaseURL: "https://example.com", Content: feedContentFull})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := items[0].Content
+	for _, want := range []string{
+		`<div class="alert alert-note" role="alert">`,
+		`<img src="https://raw.githubusercontent.com/pfeilbr/feed-playground/master/images/logo.png">`,
+		`<img src="https://raw.githubusercontent.com/pfeilbr/feed-playground/master/images/d.png" alt="d">`,
+		`<a href="https://github.com/pfeilbr/feed-playground/blob/master/docs/README.md">docs</a>`,
+		`<a href="https://example.com/post/feed-playground/#usage">usage</a>`,
+		`<a href="https://example.org">site</a>`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in %s", want, content)
+		}
+	}
+	for _, unwanted := range []string{"{{", "raw HTML omitted"} {
+		if strings.Contains(content, unwanted) {
+			t.Errorf("unexpected %q in %s", unwanted, content)
+		}
+	}
+}
+
+func TestGetFeedOptionsRequiresBaseURL(t *testing.T) {
+	os.Unsetenv("SITE_BASE_URL")
+	if _, err := getFeedOptions(); err == nil {
+		t.Error("expected an error without SITE_BASE_URL")
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/google/go-github/github"
+	"gopkg.in/yaml.v3"
+)
+
+const tomlFrontMatterFormat = "toml"
+const yamlFrontMatterFormat = "yaml"
+const jsonFrontMatterFormat = "json"
+
+// FrontMatter metadata written at the top of every post
+type FrontMatter struct {
+	Author       string     `toml:"author" yaml:"author" json:"author"`
+	AuthorURL    string     `toml:"authorURL,omitempty" yaml:"authorURL,omitempty" json:"authorURL,omitempty"`
+	AuthorAvatar string     `toml:"authorAvatar,omitempty" yaml:"authorAvatar,omitempty" json:"authorAvatar,omitempty"`
+	AuthorBio    string     `toml:"authorBio,omitempty" yaml:"authorBio,omitempty" json:"authorBio,omitempty"`
+	Layout       string     `toml:"layout,omitempty" yaml:"layout,omitempty" json:"layout,omitempty"`
+	Categories   []string   `toml:"categories" yaml:"categories" json:"categories"`
+	Date         PostDate   `toml:"date" yaml:"date" json:"date"`
+	Description  string     `toml:"description" yaml:"description" json:"description"`
+	Summary      string     `toml:"summary,omitempty" yaml:"summary,omitempty" json:"summary,omitempty"`
+	Draft        bool       `toml:"draft" yaml:"draft" json:"draft"`
+	Slug         string     `toml:"slug" yaml:"slug" json:"slug"`
+	Permalink    string     `toml:"permalink,omitempty" yaml:"permalink,omitempty" json:"permalink,omitempty"`
+	Aliases      []string   `toml:"aliases,omitempty" yaml:"aliases,omitempty" json:"aliases,omitempty"`
+	Tags         []string   `toml:"tags" yaml:"tags" json:"tags"`
+	Images       []string   `toml:"images,omitempty" yaml:"images,omitempty" json:"images,omitempty"`
+	Title        string     `toml:"title" yaml:"title" json:"title"`
+	RepoFullName string     `toml:"repoFullName" yaml:"repoFullName" json:"repoFullName"`
+	RepoHTMLURL  string     `toml:"repoHTMLURL" yaml:"repoHTMLURL" json:"repoHTMLURL"`
+	Truncated    bool       `toml:"truncated,omitempty" yaml:"truncated,omitempty" json:"truncated,omitempty"`
+	WordCount    int        `toml:"wordCount" yaml:"wordCount" json:"wordCount"`
+	ReadingTime  int        `toml:"readingTime" yaml:"readingTime" json:"readingTime"`
+	TOC          []TOCEntry `toml:"toc,omitempty" yaml:"toc,omitempty" json:"toc,omitempty"`
+}
+
+// postDateFormat the date of a post is a day, e.g. date = 2019-09-10
+const postDateFormat = "2006-01-02"
+
+// PostDate a front matter date written as a bare postDateFormat date in every format
+type PostDate struct {
+	time.Time
+}
+
+// getPostDate the date a post is published under: when the repo was created, or was last pushed to or updated.
+// 1970-01-01 when github sent none of them, so the date doesn't change between runs. ok is false then
+func getPostDate(repo *github.Repository) (date time.Time, ok bool) {
+	switch {
+	case repo.CreatedAt != nil:
+		return repo.CreatedAt.Time, true
+	case repo.PushedAt != nil:
+		return repo.PushedAt.Time, true
+	case repo.UpdatedAt != nil:
+		return repo.UpdatedAt.Time, true
+	}
+	return time.Unix(0, 0).UTC(), false
+}
+
+func parsePostDate(s string) (PostDate, error) {
+	t, err := time.Parse(postDateFormat, s)
+	if err != nil {
+		t, err = time.Parse(time.RFC3339, s)
+	}
+	return PostDate{t}, err
+}
+
+// MarshalTOML writes a TOML local date instead of a quoted string
+func (date PostDate) MarshalTOML() ([]byte, error) {
+	return []byte(date.Format(postDateFormat)), nil
+}
+
+func (date *PostDate) UnmarshalTOML(v interface{}) error {
+	switch v := v.(type) {
+	case time.Time:
+		*date = PostDate{v}
+		return nil
+	case string:
+		parsed, err := parsePostDate(v)
+		*date = parsed
+		return err
+	}
+	return fmt.Errorf("unsupported date %v", v)
+}
+
+func (date PostDate) MarshalYAML() (interface{}, error) {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: date.Format(postDateFormat)}, nil
+}
+
+func (date *PostDate) UnmarshalYAML(node *yaml.Node) error {
+	parsed, err := parsePostDate(node.Value)
+	*date = parsed
+	return err
+}
+
+func (date PostDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(date.Format(postDateFormat))
+}
+
+func (date *PostDate) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := parsePostDate(s)
+	*date = parsed
+	return err
+}
+
+func getPostCategories(repoPost *RepoPost) []string {
+	categories := make([]string, 0)
+	if repoPost.Repo.Language != nil && *repoPost.Repo.Language != "" {
+		categories = append(categories, *repoPost.Repo.Language)
+	}
+	return append(categories, "playground")
+}
+
+// newFrontMatter front matter common to every target
+func newFrontMatter(repoPost *RepoPost) FrontMatter {
+	frontMatter := FrontMatter{
+		Author:       repoPost.Author.Name,
+		AuthorURL:    repoPost.Author.URL,
+		AuthorAvatar: repoPost.Author.AvatarURL,
+		AuthorBio:    repoPost.Author.Bio,
+		Categories:   getPostCategories(repoPost),
+		Slug:         repoPost.Slug,
+		Tags:         repoPost.Tags,
+		Images:       repoPost.Images,
+		Title:        repoPost.Title,
+		RepoFullName: repoPost.Repo.GetFullName(),
+		RepoHTMLURL:  repoPost.Repo.GetHTMLURL(),
+		WordCount:    repoPost.WordCount,
+		ReadingTime:  repoPost.ReadingTime,
+		TOC:          repoPost.TableOfContents,
+	}
+	date, _ := getPostDate(repoPost.Repo)
+	frontMatter.Date = PostDate{date}
+	if frontMatter.Tags == nil {
+		frontMatter.Tags = make([]string, 0)
+	}
+	return frontMatter
+}
+
+// marshalFrontMatter serializes front matter including its delimiters
+func marshalFrontMatter(frontMatter interface{}, format string) (string, error) {
+	var buf bytes.Buffer
+
+	switch format {
+	case tomlFrontMatterFormat:
+		buf.WriteString("+++\n")
+		encoder := toml.NewEncoder(&buf)
+		encoder.Indent = ""
+		if err := encoder.Encode(frontMatter); err != nil {
+			return "", err
+		}
+		buf.WriteString("+++\n")
+	case yamlFrontMatterFormat:
+		buf.WriteString("---\n")
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(frontMatter); err != nil {
+			return "", err
+		}
+		if err := encoder.Close(); err != nil {
+			return "", err
+		}
+		buf.WriteString("---\n")
+	case jsonFrontMatterFormat:
+		b, err := json.MarshalIndent(frontMatter, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		buf.Write(b)
+		buf.WriteString("\n")
+	default:
+		return "", fmt.Errorf("unknown front matter format %q", format)
+	}
+
+	return buf.String(), nil
+}
+
+func getFrontMatterFormat(outputTarget OutputTarget) (string, error) {
+	format := frontMatterFormat
+	if format == "" {
+		format = outputTarget.FrontMatterFormat
+	}
+	for _, supported := range outputTarget.FrontMatterFormats {
+		if format == supported {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("%s does not support %q front matter", outputTarget.Name, format)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMarshalFrontMatter(t *testing.T) {
+	frontMatter := FrontMatter{
+		Author: "Brian Pfeil",
+		Date:   PostDate{time.Date(2019, 9, 10, 21, 55, 7, 0, time.UTC)},
+		Title:  `Say "hi" to C:\Users`,
+		Tags:   []string{`back\slash`, "c++"},
+	}
+
+	tests := []struct {
+		format    string
+		delimiter string
+		unmarshal func(data []byte, v interface{}) error
+		fields    []string
+	}{
+		{format: tomlFrontMatterFormat, delimiter: "+++\n", unmarshal: toml.Unmarshal, fields: []string{"date = 2019-09-10\n", "description = \"\"\n", "draft = false\n"}},
+		{format: yamlFrontMatterFormat, delimiter: "---\n", unmarshal: yaml.Unmarshal, fields: []string{"date: 2019-09-10\n", "description: \"\"\n", "draft: false\n"}},
+		{format: jsonFrontMatterFormat, delimiter: "", unmarshal: json.Unmarshal, fields: []string{`"date": "2019-09-10",`, `"description": "",`, `"draft": false,`}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			result, err := marshalFrontMatter(frontMatter, test.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(result, test.delimiter) || !strings.HasSuffix(result, test.delimiter) {
+				t.Errorf("expected %q delimiters. got %q", test.delimiter, result)
+			}
+			for _, field := range test.fields {
+				if !strings.Contains(result, field) {
+					t.Errorf("expected %q in %q", field, result)
+				}
+			}
+
+			decoded := FrontMatter{}
+			body := strings.TrimSuffix(strings.TrimPrefix(result, test.delimiter), test.delimiter)
+			if err := test.unmarshal([]byte(body), &decoded); err != nil {
+				t.Fatalf("invalid %s front matter %q: %v", test.format, result, err)
+			}
+			if decoded.Title != frontMatter.Title || strings.Join(decoded.Tags, ",") != strings.Join(frontMatter.Tags, ",") || decoded.Date.Format(postDateFormat) != "2019-09-10" {
+				t.Errorf("got %+v, want %+v", decoded, frontMatter)
+			}
+		})
+	}
+
+	if _, err := marshalFrontMatter(frontMatter, "ini"); err == nil {
+		t.Errorf("expected error for unknown format")
+	}
+}
+
+func TestGetFrontMatterFormat(t *testing.T) {
+	defer func(format string) { frontMatterFormat = format }(frontMatterFormat)
+
+	frontMatterFormat = ""
+	if format, _ := getFrontMatterFormat(outputTargets[hugoTarget]); format != tomlFrontMatterFormat {
+		t.Errorf("got %s, want %s", format, tomlFrontMatterFormat)
+	}
+
+	frontMatterFormat = tomlFrontMatterFormat
+	if _, err := getFrontMatterFormat(outputTargets[jekyllTarget]); err == nil {
+		t.Errorf("expected jekyll to reject toml front matter")
+	}
+}
@@ -1,11 +1,24 @@
 module github.com/pfeilbr/create-blog-post-from-repo
 
-go 1.14
+go 1.18
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/google/go-github v17.0.0+incompatible
-	github.com/google/go-querystring v1.0.0 // indirect
 	github.com/joho/godotenv v1.3.0
 	github.com/sirupsen/logrus v1.6.0
+	github.com/yuin/goldmark v1.5.6
+	golang.org/x/image v0.18.0
+	golang.org/x/net v0.25.0
 	golang.org/x/oauth2 v0.0.0-20200107190931-bf48bf16ab8d
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/golang/protobuf v1.2.0 // indirect
+	github.com/google/go-querystring v1.0.0 // indirect
+	github.com/konsorten/go-windows-terminal-sequences v1.0.3 // indirect
+	golang.org/x/sys v0.20.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	google.golang.org/appengine v1.4.0 // indirect
 )
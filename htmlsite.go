@@ -0,0 +1,295 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+const htmlTarget = "html"
+
+const defaultHTMLSiteTitle = "Projects"
+
+// HTMLPage data every page of the static HTML site is rendered with
+type HTMLPage struct {
+	SiteTitle string
+	Title     string
+	Root      string
+	Post      *RepoPost
+	Body      template.HTML
+	Posts     []RepoPost
+	Tags      []HTMLTag
+}
+
+// HTMLTag a tag and the posts tagged with it
+type HTMLTag struct {
+	Name  string
+	Posts []RepoPost
+}
+
+func getHTMLSiteTitle() string {
+	if title := getConfig().Outputs.HTMLSiteTitle; title != "" {
+		return title
+	}
+	return defaultHTMLSiteTitle
+}
+
+// getTagFileName file name of a tag listing page. a tag that is already a slug keeps its name, aws becomes aws.html.
+// any other tag gets part of a hash of it so tags that slugify the same don't share a page,
+// c++ becomes cpp-<hash>.html, c# c-<hash>.html and a tag with no ASCII letters tag-<hash>.html
+func getTagFileName(tag string) string {
+	slug := slugify(strings.ReplaceAll(tag, "+", "p"))
+	if slug == tag {
+		return slug + ".html"
+	}
+	if slug == "" {
+		slug = "tag"
+	}
+	return slug + "-" + getMD5Hash(tag)[:6] + ".html"
+}
+
+func getHTMLPostFileName(repoPost *RepoPost) string {
+	return "posts/" + repoPost.Slug + ".html"
+}
+
+// getRenderedHTMLSanitizerPolicy the sanitizer policy plus the tags and attributes goldmark and the html shortcodes write
+func getRenderedHTMLSanitizerPolicy() (HTMLSanitizerPolicy, error) {
+	policy, err := getHTMLSanitizerPolicy()
+	if err != nil {
+		return policy, err
+	}
+	for _, tag := range []string{"a", "blockquote", "br", "code", "del", "div", "em", "h1", "h2", "h3", "h4", "h5", "h6", "hr", "img", "input",
+		"li", "ol", "p", "pre", "strong", "table", "tbody", "td", "th", "thead", "tr", "ul"} {
+		policy.Tags[tag] = sanitizeActionAllow
+	}
+	for tag, attributes := range map[string][]string{
+		"*":     {"class"},
+		"div":   {"role"},
+		"input": {"type", "checked", "disabled"},
+		"h1":    {"id"}, "h2": {"id"}, "h3": {"id"}, "h4": {"id"}, "h5": {"id"}, "h6": {"id"},
+	} {
+		policy.Attributes[tag] = append(append([]string{}, policy.Attributes[tag]...), attributes...)
+	}
+	return policy, nil
+}
+
+// unsafeLinkTransformer empties the destination of markdown links and images with a url isSafeURL rejects
+type unsafeLinkTransformer struct {
+	repoName string
+}
+
+func (transformer unsafeLinkTransformer) Transform(document *ast.Document, reader text.Reader, pc parser.Context) {
+	ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := node.(type) {
+		case *ast.Link:
+			if !isSafeURL(string(node.Destination)) {
+				log.Printf("renderMarkdownToHTML(%s) strip link destination %q\n", transformer.repoName, node.Destination)
+				node.Destination = nil
+			}
+		case *ast.Image:
+			if !isSafeURL(string(node.Destination)) {
+				log.Printf("renderMarkdownToHTML(%s) strip image destination %q\n", transformer.repoName, node.Destination)
+				node.Destination = nil
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// renderMarkdownToHTML the markdown went through sanitizePostBody, the rendered HTML goes through the sanitizer again
+// so nothing the markdown sanitizer missed reaches the site. link and image destinations are checked before rendering
+func renderMarkdownToHTML(repoName string, markdown string) (template.HTML, error) {
+	var buf bytes.Buffer
+	md := goldmark.New(
+		// GFM with table cells aligned by the align attribute the sanitizer allows instead of a style attribute
+		goldmark.WithExtensions(extension.Linkify, extension.Strikethrough, extension.TaskList,
+			extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute))),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID(),
+			parser.WithASTTransformers(util.Prioritized(unsafeLinkTransformer{repoName: repoName}, 1000))),
+		goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+	)
+	if err := md.Convert([]byte(markdown), &buf); err != nil {
+		return "", err
+	}
+
+	policy, err := getRenderedHTMLSanitizerPolicy()
+	if err != nil {
+		log.Printf("getRenderedHTMLSanitizerPolicy() failed\n")
+		return "", err
+	}
+	sanitized, removals := policy.sanitizeHTMLFragment(buf.String())
+	for _, removal := range removals {
+		log.Printf("renderMarkdownToHTML(%s) %s\n", repoName, removal)
+	}
+	return template.HTML(sanitized), nil
+}
+
+func loadHTMLTemplates(templateDirectory string) (*template.Template, error) {
+	funcMap := template.FuncMap{"tagFileName": getTagFileName}
+	for name, fn := range templateFuncMap {
+		funcMap[name] = fn
+	}
+
+	templates, err := fs.Sub(defaultTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+	t, err := template.New("").Funcs(funcMap).ParseFS(templates, "html/*.html")
+	if err != nil {
+		return nil, err
+	}
+
+	if templateDirectory != "" {
+		overrides := os.DirFS(templateDirectory)
+		if matches, _ := fs.Glob(overrides, "html/*.html"); len(matches) > 0 {
+			if t, err = t.ParseFS(overrides, "html/*.html"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return t, nil
+}
+
+// executeHTMLTemplate renders the first of names that is a defined template
+func executeHTMLTemplate(page HTMLPage, names ...string) (string, error) {
+	t, err := loadHTMLTemplates(templateDirectory)
+	if err != nil {
+		log.Printf("loadHTMLTemplates(%s) failed\n", templateDirectory)
+		return "", err
+	}
+
+	name := names[len(names)-1]
+	for _, candidate := range names {
+		if t.Lookup(candidate) != nil {
+			name = candidate
+			break
+		}
+	}
+
+	page.SiteTitle = getHTMLSiteTitle()
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, name, page); err != nil {
+		log.Printf("template.ExecuteTemplate(%s) failed\n", name)
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// renderHTMLPost a standalone HTML page for a post
+func renderHTMLPost(repoPost *RepoPost) (string, error) {
+	body, err := renderMarkdownToHTML(*repoPost.Repo.Name, repoPost.MarkdownBody)
+	if err != nil {
+		log.Printf("renderMarkdownToHTML(%s) failed\n", *repoPost.Repo.Name)
+		return "", err
+	}
+
+	// a rule selected tutorial.md uses tutorial.html when there is one
+	templateName := strings.TrimSuffix(repoPost.TemplateName, filepath.Ext(repoPost.TemplateName)) + ".html"
+	return executeHTMLTemplate(HTMLPage{Title: repoPost.Title, Root: "../", Post: repoPost, Body: body}, templateName, "post.html")
+}
+
+func sortRepoPostsByCreatedAt(repoPosts []RepoPost) []RepoPost {
+	sorted := append([]RepoPost{}, repoPosts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Repo.GetCreatedAt().After(sorted[j].Repo.GetCreatedAt().Time)
+	})
+	return sorted
+}
+
+func getHTMLTags(repoPosts []RepoPost) []HTMLTag {
+	names, postsByTag := groupRepoPostsByTerm(repoPosts, func(repoPost *RepoPost) []string { return repoPost.Tags })
+
+	tags := make([]HTMLTag, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, HTMLTag{Name: name, Posts: postsByTag[name]})
+	}
+	return tags
+}
+
+func writeSiteFile(destinationDirectory string, name string, contents []byte) error {
+	path := filepath.Join(destinationDirectory, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		log.Printf("os.MkdirAll(%s) failed\n", filepath.Dir(path))
+		return err
+	}
+	if err := ioutil.WriteFile(path, contents, 0644); err != nil {
+		log.Printf("ioutil.WriteFile(%s) failed\n", path)
+		return err
+	}
+	return nil
+}
+
+func copyHTMLAssets(destinationDirectory string) error {
+	assets, err := fs.Sub(defaultTemplates, "templates/html")
+	if err != nil {
+		return err
+	}
+	sources := []fs.FS{assets}
+	if templateDirectory != "" {
+		sources = append(sources, os.DirFS(filepath.Join(templateDirectory, "html")))
+	}
+
+	for _, source := range sources {
+		err := fs.WalkDir(source, "assets", func(name string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			b, err := fs.ReadFile(source, name)
+			if err != nil {
+				return err
+			}
+			return writeSiteFile(destinationDirectory, name, b)
+		})
+		if err != nil && !os.IsNotExist(err) {
+			log.Printf("copyHTMLAssets(%s) failed\n", destinationDirectory)
+			return err
+		}
+	}
+	return nil
+}
+
+// createHTMLSite writes the index page, a listing page per tag and the static assets next to the post pages
+func createHTMLSite(repoPosts []RepoPost, destinationDirectory string) error {
+	siteTitle := getHTMLSiteTitle()
+	sortedRepoPosts := sortRepoPostsByCreatedAt(repoPosts)
+	tags := getHTMLTags(sortedRepoPosts)
+
+	index, err := executeHTMLTemplate(HTMLPage{Title: siteTitle, Posts: sortedRepoPosts, Tags: tags}, "index.html")
+	if err != nil {
+		log.Printf("executeHTMLTemplate(index.html) failed\n")
+		return err
+	}
+	if err := writeSiteFile(destinationDirectory, "index.html", []byte(index)); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		page, err := executeHTMLTemplate(HTMLPage{Title: tag.Name, Root: "../", Posts: tag.Posts}, "tag.html")
+		if err != nil {
+			log.Printf("executeHTMLTemplate(tag.html, %s) failed\n", tag.Name)
+			return err
+		}
+		if err := writeSiteFile(destinationDirectory, "tags/"+getTagFileName(tag.Name), []byte(page)); err != nil {
+			return err
+		}
+	}
+
+	return copyHTMLAssets(destinationDirectory)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+func TestCreateHTMLSite(t *testing.T) {
+	destinationDirectory, err := ioutil.TempDir("", "site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(destinationDirectory)
+
+	repoPosts := make([]RepoPost, 0)
+	for i, name := range []string{"older-playground", "newer-playground"} {
+		repo := newTestRepo(name)
+		repo.CreatedAt = &github.Timestamp{Time: time.Date(2019, time.Month(1+i), 1, 0, 0, 0, 0, time.UTC)}
+		repoPost := RepoPost{Repo: repo, Title: name, Slug: name, Tags: []string{"aws", "c++"}, MarkdownBody: "## Usage\n\n<kbd>x</kbd> `code`\n"}
+		repoPost.PostFileName = getHTMLPostFileName(&repoPost)
+		contents, err := renderHTMLPost(&repoPost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repoPost.PostFileContents = contents
+		if err := createMarkdownPostFile(repoPost, destinationDirectory); err != nil {
+			t.Fatal(err)
+		}
+		repoPosts = append(repoPosts, repoPost)
+	}
+
+	if err := createHTMLSite(repoPosts, destinationDirectory); err != nil {
+		t.Fatal(err)
+	}
+
+	read := func(name string) string {
+		b, err := ioutil.ReadFile(filepath.Join(destinationDirectory, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	post := read("posts/newer-playground.html")
+	for _, want := range []string{`<h2 id="usage">Usage</h2>`, "<kbd>x</kbd>", `href="../assets/style.css"`, `href="../tags/` + getTagFileName("c++") + `"`} {
+		if !strings.Contains(post, want) {
+			t.Errorf("expected %q in post %s", want, post)
+		}
+	}
+
+	index := read("index.html")
+	if strings.Index(index, "newer-playground") > strings.Index(index, "older-playground") {
+		t.Errorf("expected newest post first in %s", index)
+	}
+	if !strings.Contains(index, `href="posts/older-playground.html"`) || !strings.Contains(index, "c&#43;&#43; (2)") {
+		t.Errorf("unexpected index %s", index)
+	}
+
+	if tag := read("tags/" + getTagFileName("c++")); !strings.Contains(tag, `href="../posts/newer-playground.html"`) {
+		t.Errorf("unexpected tag page %s", tag)
+	}
+	if css := read("assets/style.css"); len(css) == 0 {
+		t.Errorf("expected assets to be copied")
+	}
+}
+
+func TestGetTagFileName(t *testing.T) {
+	if name := getTagFileName("aws"); name != "aws.html" {
+		t.Errorf("expected aws.html, got %s", name)
+	}
+	names := make(map[string]string)
+	for _, tag := range []string{"c", "c#", "c++", "cpp", "日本語", "中文"} {
+		name := getTagFileName(tag)
+		if other, ok := names[name]; ok {
+			t.Errorf("%s and %s share %s", tag, other, name)
+		}
+		if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "-") {
+			t.Errorf("unexpected file name %s for %s", name, tag)
+		}
+		names[name] = tag
+	}
+}
+
+func TestRenderMarkdownToHTMLSanitizes(t *testing.T) {
+	// html that got past the markdown sanitizer is caught after rendering
+	body, err := renderMarkdownToHTML("html-playground", "<div>\n```\n</div>\n<script>alert(1)</script>\n\n- [x] done\n\n| a |\n|:-|\n| 1 |\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(body), "<script") {
+		t.Errorf("expected the script to be removed, got %s", body)
+	}
+	for _, want := range []string{`<input checked="" disabled="" type="checkbox"`, `<td align="left">1</td>`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected %q in %s", want, body)
+		}
+	}
+}
+
+func TestRenderMarkdownToHTMLUnsafeURLs(t *testing.T) {
+	body, err := renderMarkdownToHTML("html-playground", "<a href=\"java&#9;script:alert(1)\">a</a>\n\n[b](javascript:alert(1)) ![c](java%09script:alert(1)) [d](https://example.com)\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(strings.ToLower(string(body)), "script") {
+		t.Errorf("expected the javascript urls to be removed, got %s", body)
+	}
+	if !strings.Contains(string(body), `<a href="https://example.com">d</a>`) {
+		t.Errorf("expected the https link to be kept, got %s", body)
+	}
+}
+
+func TestGetGFMTransformOptionsHTMLTarget(t *testing.T) {
+	defer func(name string) { target = name }(target)
+	target = htmlTarget
+	options := getGFMTransformOptions()
+	for construct, shortcode := range options.Shortcodes {
+		if shortcode != htmlShortcode {
+			t.Errorf("expected %s to render as html, got %s", construct, shortcode)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	urlpath "path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/google/go-github/github"
+)
+
+const defaultExpandSourceLinksMaxBytes = 4096
+
+var rawContentBaseURL = "https://raw.githubusercontent.com"
+
+var includeDirectiveRegexp = regexp.MustCompile(`<!--\s*blog:include\s+(.*?)\s*-->`)
+var includeAttributeRegexp = regexp.MustCompile(`([a-zA-Z]+)=("[^"]*"|\S+)`)
+var markdownLinkRegexp = regexp.MustCompile(`(!?)\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+var lineAnchorRegexp = regexp.MustCompile(`^L(\d+)(?:-L(\d+))?$`)
+
+var languagesByExtension = map[string]string{
+	".bash":  "bash",
+	".c":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".cs":    "csharp",
+	".css":   "css",
+	".go":    "go",
+	".h":     "c",
+	".hpp":   "cpp",
+	".html":  "html",
+	".java":  "java",
+	".js":    "js",
+	".json":  "json",
+	".jsx":   "jsx",
+	".kt":    "kotlin",
+	".lua":   "lua",
+	".mjs":   "js",
+	".php":   "php",
+	".py":    "python",
+	".rb":    "ruby",
+	".rs":    "rust",
+	".scala": "scala",
+	".sh":    "sh",
+	".sql":   "sql",
+	".swift": "swift",
+	".tf":    "hcl",
+	".toml":  "toml",
+	".ts":    "ts",
+	".tsx":   "tsx",
+	".xml":   "xml",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+}
+
+// SourceInclude a file, or a range of lines in a file, from a repo to embed in a post
+type SourceInclude struct {
+	Path      string
+	StartLine int
+	EndLine   int
+	Language  string
+}
+
+func getSourceLanguage(filePath string) string {
+	if language, ok := languagesByExtension[strings.ToLower(urlpath.Ext(filePath))]; ok {
+		return language
+	}
+	switch urlpath.Base(filePath) {
+	case "Dockerfile":
+		return "dockerfile"
+	case "Makefile":
+		return "makefile"
+	}
+	return ""
+}
+
+// getRepoRef the repo's default branch, or readmeRef when github didn't name one.
+// README.md, embedded source files and tag rule files are all read from it
+func getRepoRef(repo *github.Repository) string {
+	if ref := repo.GetDefaultBranch(); ref != "" {
+		return ref
+	}
+	return readmeRef
+}
+
+func getRawFileURL(repo *github.Repository, filePath string) string {
+	return rawContentBaseURL + "/" + *repo.FullName + "/" + getRepoRef(repo) + "/" + strings.TrimPrefix(filePath, "/")
+}
+
+func getFileHTMLURL(repo *github.Repository, filePath string) string {
+	return *repo.HTMLURL + "/blob/" + getRepoRef(repo) + "/" + strings.TrimPrefix(filePath, "/")
+}
+
+func parseLineRange(lines string) (int, int, error) {
+	parts := strings.SplitN(lines, "-", 2)
+	start, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid line range %q", lines)
+	}
+	end := start
+	if len(parts) == 2 {
+		if end, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+			return 0, 0, fmt.Errorf("invalid line range %q", lines)
+		}
+	}
+	if start < 1 || end < start {
+		return 0, 0, fmt.Errorf("invalid line range %q", lines)
+	}
+	return start, end, nil
+}
+
+func parseIncludeDirective(attributesString string) (SourceInclude, error) {
+	include := SourceInclude{}
+	for _, match := range includeAttributeRegexp.FindAllStringSubmatch(attributesString, -1) {
+		value := strings.Trim(match[2], "\"")
+		switch match[1] {
+		case "path":
+			include.Path = value
+		case "lines":
+			start, end, err := parseLineRange(value)
+			if err != nil {
+				return include, err
+			}
+			include.StartLine, include.EndLine = start, end
+		case "lang":
+			include.Language = value
+		}
+	}
+
+	// the path is in the repo, ../ can't reach the files of another repo
+	include.Path = strings.TrimPrefix(urlpath.Clean("/"+include.Path), "/")
+	if include.Path == "" {
+		return include, fmt.Errorf("blog:include is missing a path")
+	}
+	if include.Language == "" {
+		include.Language = getSourceLanguage(include.Path)
+	}
+	return include, nil
+}
+
+func selectLines(contents string, start int, end int) string {
+	if start == 0 {
+		return contents
+	}
+	lines := strings.Split(contents, "\n")
+	if start > len(lines) {
+		return ""
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+	return strings.Join(lines[start-1:end], "\n")
+}
+
+func getSourceIncludeContents(repo *github.Repository, include SourceInclude) (string, error) {
+	contents, err := getURLResponseBody(getRawFileURL(repo, include.Path), useCache)
+	if err != nil {
+		log.Printf("getURLResponseBody(%s) failed\n", getRawFileURL(repo, include.Path))
+		return "", err
+	}
+	return selectLines(contents, include.StartLine, include.EndLine), nil
+}
+
+func fencedCodeBlock(code string, language string) string {
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + strings.TrimRight(code, "\n") + "\n" + fence
+}
+
+// expandIncludeDirectives replaces every <!-- blog:include path=... lines=... --> with the referenced code.
+// a directive in the middle of a line gets its code block on lines of its own
+func expandIncludeDirectives(repo *github.Repository, markdown string) string {
+	return mapMarkdownText(markdown, func(text string) string {
+		var b strings.Builder
+		end := 0
+		for _, match := range includeDirectiveRegexp.FindAllStringSubmatchIndex(text, -1) {
+			b.WriteString(text[end:match[0]])
+			end = match[1]
+			directive := text[match[0]:match[1]]
+			include, err := parseIncludeDirective(text[match[2]:match[3]])
+			if err != nil {
+				log.Printf("parseIncludeDirective(%s) failed: %v\n", *repo.Name, err)
+				b.WriteString(directive)
+				continue
+			}
+
+			code, err := getSourceIncludeContents(repo, include)
+			if err != nil {
+				log.Printf("getSourceIncludeContents(%s, %s) failed\n", *repo.Name, include.Path)
+				b.WriteString("See [" + include.Path + "](" + getFileHTMLURL(repo, include.Path) + ")")
+				continue
+			}
+			if match[0] > 0 && text[match[0]-1] != '\n' {
+				b.WriteString("\n")
+			}
+			b.WriteString(fencedCodeBlock(code, include.Language))
+			if end < len(text) && text[end] != '\n' {
+				b.WriteString("\n")
+			}
+		}
+		b.WriteString(text[end:])
+		return b.String()
+	})
+}
+
+// getSourceLinkInclude resolves a markdown link target to a file in the repo
+func getSourceLinkInclude(repo *github.Repository, target string) (SourceInclude, bool) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return SourceInclude{}, false
+	}
+
+	filePath := u.Path
+	if u.IsAbs() {
+		blobPrefix := "/" + *repo.FullName + "/blob/" + getRepoRef(repo) + "/"
+		if u.Host != "github.com" || !strings.HasPrefix(u.Path, blobPrefix) {
+			return SourceInclude{}, false
+		}
+		filePath = strings.TrimPrefix(u.Path, blobPrefix)
+	} else if u.Host != "" {
+		return SourceInclude{}, false
+	}
+
+	filePath = strings.TrimPrefix(urlpath.Clean("/"+filePath), "/")
+	language := getSourceLanguage(filePath)
+	if filePath == "" || language == "" {
+		return SourceInclude{}, false
+	}
+
+	include := SourceInclude{Path: filePath, Language: language}
+	if match := lineAnchorRegexp.FindStringSubmatch(u.Fragment); match != nil {
+		include.StartLine, _ = strconv.Atoi(match[1])
+		include.EndLine = include.StartLine
+		if match[2] != "" {
+			include.EndLine, _ = strconv.Atoi(match[2])
+		}
+	}
+	return include, true
+}
+
+// expandSourceLinks appends a fenced code block after each paragraph that links to a small source file in the repo
+func expandSourceLinks(repo *github.Repository, markdown string, maxBytes int) string {
+	return mapMarkdownText(markdown, func(text string) string {
+		paragraphs := strings.Split(text, "\n\n")
+		for i, paragraph := range paragraphs {
+			codeBlocks := make([]string, 0)
+			expanded := make(map[string]bool)
+			for _, match := range markdownLinkRegexp.FindAllStringSubmatch(paragraph, -1) {
+				if match[1] == "!" || expanded[match[3]] {
+					continue
+				}
+				include, ok := getSourceLinkInclude(repo, match[3])
+				if !ok {
+					continue
+				}
+				expanded[match[3]] = true
+
+				code, err := getSourceIncludeContents(repo, include)
+				if err != nil {
+					continue
+				}
+				if len(code) > maxBytes {
+					if debug {
+						log.Printf("expandSourceLinks(%s) skipping %s. %d bytes\n", *repo.Name, include.Path, len(code))
+					}
+					continue
+				}
+				codeBlocks = append(codeBlocks, fencedCodeBlock(code, include.Language))
+			}
+
+			if len(codeBlocks) > 0 {
+				trailing := paragraph[len(strings.TrimRight(paragraph, "\n")):]
+				paragraphs[i] = strings.TrimRight(paragraph, "\n") + "\n\n" + strings.Join(codeBlocks, "\n\n") + trailing
+			}
+		}
+		return strings.Join(paragraphs, "\n\n")
+	})
+}
+
+func getExpandSourceLinksMaxBytes() int {
+	maxBytes := getConfig().Body.ExpandSourceLinksMaxBytes
+	if maxBytes <= 0 {
+		return defaultExpandSourceLinksMaxBytes
+	}
+	return maxBytes
+}
+
+// embedSourceFiles inlines code referenced by include directives and, when enabled, links to small source files
+func embedSourceFiles(repo *github.Repository, markdown string) string {
+	markdown = expandIncludeDirectives(repo, markdown)
+	if getConfig().Body.ExpandSourceLinks {
+		markdown = expandSourceLinks(repo, markdown, getExpandSourceLinksMaxBytes())
+	}
+	return markdown
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func TestEmbedSourceFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pfeilbr/include-playground/master/src/handler.js":
+			w.Write([]byte("line1\nline2\nline3\nline4\n"))
+		case "/pfeilbr/include-playground/master/main.go":
+			w.Write([]byte("package main\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	defer func(baseURL string, cache bool) {
+		rawContentBaseURL, useCache = baseURL, cache
+	}(rawContentBaseURL, useCache)
+	rawContentBaseURL, useCache = server.URL, false
+
+	repo := newTestRepo("include-playground")
+
+	t.Run("directive", func(t *testing.T) {
+		result := expandIncludeDirectives(repo, "before\n<!-- blog:include path=src/handler.js lines=2-3 -->\nafter\n")
+		want := "before\n```js\nline2\nline3\n```\nafter\n"
+		if result != want {
+			t.Errorf("got %q, want %q", result, want)
+		}
+	})
+
+	t.Run("directive mid-line", func(t *testing.T) {
+		result := expandIncludeDirectives(repo, "text <!-- blog:include path=src/handler.js lines=2 --> more\n")
+		want := "text \n```js\nline2\n```\n more\n"
+		if result != want {
+			t.Errorf("got %q, want %q", result, want)
+		}
+	})
+
+	t.Run("path outside the repo", func(t *testing.T) {
+		result := expandIncludeDirectives(repo, "<!-- blog:include path=../../other/repo/master/main.go -->")
+		want := "See [other/repo/master/main.go](https://github.com/pfeilbr/include-playground/blob/master/other/repo/master/main.go)"
+		if result != want {
+			t.Errorf("got %q, want %q", result, want)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		result := expandIncludeDirectives(repo, "<!-- blog:include path=missing.js -->")
+		want := "See [missing.js](https://github.com/pfeilbr/include-playground/blob/master/missing.js)"
+		if result != want {
+			t.Errorf("got %q, want %q", result, want)
+		}
+	})
+
+	t.Run("source links", func(t *testing.T) {
+		result := expandSourceLinks(repo, "see [main.go](main.go) and [docs](docs/README.md)\n\nmore", 100)
+		want := "see [main.go](main.go) and [docs](docs/README.md)\n\n```go\npackage main\n```\n\nmore"
+		if result != want {
+			t.Errorf("got %q, want %q", result, want)
+		}
+
+		if result := expandSourceLinks(repo, "see [main.go](main.go)", 5); strings.Contains(result, "```") {
+			t.Errorf("expected large file to be skipped. got %q", result)
+		}
+	})
+}
+
+func TestGetRepoRef(t *testing.T) {
+	repo := newTestRepo("lambda-playground")
+	if url := getRawFileURL(repo, "/go.mod"); url != rawContentBaseURL+"/pfeilbr/lambda-playground/master/go.mod" {
+		t.Errorf("expected readmeRef without a default branch, got %s", url)
+	}
+	repo.DefaultBranch = github.String("main")
+	if url := getRawFileURL(repo, "go.mod"); url != rawContentBaseURL+"/pfeilbr/lambda-playground/main/go.mod" {
+		t.Errorf("expected the default branch, got %s", url)
+	}
+	if url := getFileHTMLURL(repo, "go.mod"); url != "https://github.com/pfeilbr/lambda-playground/blob/main/go.mod" {
+		t.Errorf("expected the default branch, got %s", url)
+	}
+	if include, ok := getSourceLinkInclude(repo, "https://github.com/pfeilbr/lambda-playground/blob/main/main.go"); !ok || include.Path != "main.go" {
+		t.Errorf("expected a link on the default branch to be included, got %v", include)
+	}
+}
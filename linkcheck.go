@@ -0,0 +1,383 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+	"golang.org/x/net/html"
+)
+
+const defaultLinkCheckConcurrency = 8
+const defaultLinkCheckHostInterval = 500 * time.Millisecond
+const defaultLinkCheckTimeout = 10 * time.Second
+const defaultLinkCheckBrokenCacheTTL = 24 * time.Hour
+
+// LinkCheckOptions how links are checked. broken links are recorded in the URL response cache
+// and not requested again for BrokenCacheTTL, 0 checks every link
+type LinkCheckOptions struct {
+	Concurrency    int
+	HostInterval   time.Duration
+	Timeout        time.Duration
+	BrokenCacheTTL time.Duration
+}
+
+// LinkCheckResult outcome of checking a single URL
+type LinkCheckResult struct {
+	URL    string `json:"url"`
+	Broken bool   `json:"broken"`
+	Error  string `json:"error,omitempty"`
+}
+
+// PostLinkReport broken links found in a post
+type PostLinkReport struct {
+	RepoName     string            `json:"repoName"`
+	PostFileName string            `json:"postFileName"`
+	LinkCount    int               `json:"linkCount"`
+	BrokenLinks  []LinkCheckResult `json:"brokenLinks"`
+}
+
+// getHTMLURLs the href and src attributes of raw HTML
+func getHTMLURLs(raw string) []string {
+	urls := make([]string, 0)
+	z := html.NewTokenizer(strings.NewReader(raw))
+	for tokenType := z.Next(); tokenType != html.ErrorToken; tokenType = z.Next() {
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+		for _, attribute := range z.Token().Attr {
+			if attribute.Key == "href" || attribute.Key == "src" {
+				urls = append(urls, strings.TrimSpace(attribute.Val))
+			}
+		}
+	}
+	return urls
+}
+
+func getSegmentsText(segments *text.Segments, source []byte) string {
+	var raw strings.Builder
+	for i := 0; i < segments.Len(); i++ {
+		segment := segments.At(i)
+		raw.Write(segment.Value(source))
+	}
+	return raw.String()
+}
+
+// extractURLs returns every http(s) URL the markdown links to outside of code, in order of first appearance.
+// links, images and autolinks come from a CommonMark parser, so a URL with parentheses is kept whole
+func extractURLs(markdown string) []string {
+	urls := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(link string) {
+		if u, err := url.Parse(link); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return
+		}
+		if !seen[link] {
+			seen[link] = true
+			urls = append(urls, link)
+		}
+	}
+
+	source := []byte(markdown)
+	document := goldmark.New(goldmark.WithExtensions(extension.GFM)).Parser().Parse(text.NewReader(source))
+	ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := node.(type) {
+		case *ast.Link:
+			add(string(node.Destination))
+		case *ast.Image:
+			add(string(node.Destination))
+		case *ast.AutoLink:
+			if node.AutoLinkType == ast.AutoLinkURL {
+				link := string(node.URL(source))
+				if strings.HasPrefix(link, "www.") {
+					link = "http://" + link
+				}
+				add(link)
+			}
+		case *ast.HTMLBlock:
+			for _, link := range getHTMLURLs(getSegmentsText(node.Lines(), source)) {
+				add(link)
+			}
+		case *ast.RawHTML:
+			for _, link := range getHTMLURLs(getSegmentsText(node.Segments, source)) {
+				add(link)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return urls
+}
+
+// hostRateLimiter spaces out requests to the same host by a fixed interval
+type hostRateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     map[string]time.Time
+}
+
+func newHostRateLimiter(interval time.Duration) *hostRateLimiter {
+	return &hostRateLimiter{interval: interval, next: make(map[string]time.Time)}
+}
+
+func (limiter *hostRateLimiter) wait(host string) {
+	limiter.mu.Lock()
+	now := time.Now()
+	next, ok := limiter.next[host]
+	if !ok || next.Before(now) {
+		next = now
+	}
+	limiter.next[host] = next.Add(limiter.interval)
+	limiter.mu.Unlock()
+
+	time.Sleep(next.Sub(now))
+}
+
+// requestLinkStatus the status code of a request for link, without reading the body. a GET only asks for the first byte
+func requestLinkStatus(client *http.Client, method string, link string) (int, error) {
+	req, err := http.NewRequest(method, link, nil)
+	if err != nil {
+		return 0, err
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%s error: %v", method, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// getLinkCheckCacheFilePath where the result of checking link is kept in the URL response cache, apart from its response body
+func getLinkCheckCacheFilePath(link string) string {
+	return getURLResponseCacheFilePath("check-links " + link)
+}
+
+// CachedLinkCheckResult a broken link result and when it was checked
+type CachedLinkCheckResult struct {
+	LinkCheckResult
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// readCachedLinkCheckResult a broken result for link checked less than ttl ago
+func readCachedLinkCheckResult(link string, ttl time.Duration) (LinkCheckResult, bool) {
+	var cached CachedLinkCheckResult
+	b, err := ioutil.ReadFile(getLinkCheckCacheFilePath(link))
+	if err != nil {
+		return cached.LinkCheckResult, false
+	}
+	if err := json.Unmarshal(b, &cached); err != nil || cached.URL != link || !cached.Broken || time.Since(cached.CheckedAt) >= ttl {
+		return cached.LinkCheckResult, false
+	}
+	return cached.LinkCheckResult, true
+}
+
+func writeCachedLinkCheckResult(result LinkCheckResult) {
+	b, err := json.Marshal(CachedLinkCheckResult{LinkCheckResult: result, CheckedAt: time.Now()})
+	if err != nil {
+		return
+	}
+	path := getLinkCheckCacheFilePath(result.URL)
+	os.MkdirAll(getURLResponseCacheDirectory(), os.ModePerm)
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		log.Printf("ioutil.WriteFile(%s) failed\n", path)
+	}
+}
+
+// checkLink requests link with HEAD, and with a ranged GET when HEAD fails as some hosts don't answer HEAD.
+// a link that answered with an error status is recorded in the URL response cache and not requested again for brokenCacheTTL.
+// working links and links that didn't answer at all are checked again every run
+func checkLink(client *http.Client, link string, limiter *hostRateLimiter, brokenCacheTTL time.Duration) LinkCheckResult {
+	if brokenCacheTTL > 0 {
+		if result, ok := readCachedLinkCheckResult(link, brokenCacheTTL); ok {
+			return result
+		}
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return LinkCheckResult{URL: link, Broken: true, Error: err.Error()}
+	}
+
+	limiter.wait(u.Host)
+	statusCode, err := requestLinkStatus(client, http.MethodHead, link)
+	if err != nil || statusCode >= 400 {
+		limiter.wait(u.Host)
+		statusCode, err = requestLinkStatus(client, http.MethodGet, link)
+	}
+	if err != nil {
+		return LinkCheckResult{URL: link, Broken: true, Error: err.Error()}
+	}
+
+	if statusCode < 400 {
+		return LinkCheckResult{URL: link}
+	}
+	result := LinkCheckResult{URL: link, Broken: true, Error: (&URLStatusError{StatusCode: statusCode}).Error()}
+	if brokenCacheTTL > 0 {
+		writeCachedLinkCheckResult(result)
+	}
+	return result
+}
+
+// checkLinks checks every URL in the posts concurrently. each URL is only requested once.
+func checkLinks(repoPosts []RepoPost, options LinkCheckOptions) []PostLinkReport {
+	linksByPost := make([][]string, len(repoPosts))
+	uniqueLinks := make([]string, 0)
+	seen := make(map[string]bool)
+	for i, repoPost := range repoPosts {
+		linksByPost[i] = extractURLs(repoPost.MarkdownBody)
+		for _, link := range linksByPost[i] {
+			if !seen[link] {
+				seen[link] = true
+				uniqueLinks = append(uniqueLinks, link)
+			}
+		}
+	}
+
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultLinkCheckConcurrency
+	}
+	limiter := newHostRateLimiter(options.HostInterval)
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = defaultLinkCheckTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+
+	jobs := make(chan string)
+	results := make(map[string]LinkCheckResult)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for link := range jobs {
+				result := checkLink(client, link, limiter, options.BrokenCacheTTL)
+				if debug {
+					log.Printf("checkLink(%s) broken: %t\n", link, result.Broken)
+				}
+				mu.Lock()
+				results[link] = result
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, link := range uniqueLinks {
+		jobs <- link
+	}
+	close(jobs)
+	wg.Wait()
+
+	reports := make([]PostLinkReport, 0)
+	for i, repoPost := range repoPosts {
+		report := PostLinkReport{
+			RepoName:     *repoPost.Repo.Name,
+			PostFileName: repoPost.PostFileName,
+			LinkCount:    len(linksByPost[i]),
+			BrokenLinks:  make([]LinkCheckResult, 0),
+		}
+		for _, link := range linksByPost[i] {
+			if results[link].Broken {
+				report.BrokenLinks = append(report.BrokenLinks, results[link])
+			}
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+func getLinkCheckOptions() LinkCheckOptions {
+	options := LinkCheckOptions{
+		Concurrency:    defaultLinkCheckConcurrency,
+		HostInterval:   defaultLinkCheckHostInterval,
+		Timeout:        defaultLinkCheckTimeout,
+		BrokenCacheTTL: defaultLinkCheckBrokenCacheTTL,
+	}
+	config := getConfig()
+	if config.LinkCheck.Concurrency > 0 {
+		options.Concurrency = config.LinkCheck.Concurrency
+	}
+	if interval := config.LinkCheck.HostIntervalMS; interval != nil && *interval >= 0 {
+		options.HostInterval = time.Duration(*interval) * time.Millisecond
+	}
+	if timeout := config.LinkCheck.TimeoutMS; timeout != nil && *timeout > 0 {
+		options.Timeout = time.Duration(*timeout) * time.Millisecond
+	}
+	if ttl := config.LinkCheck.BrokenCacheTTLMinutes; ttl != nil && *ttl >= 0 {
+		options.BrokenCacheTTL = time.Duration(*ttl) * time.Minute
+	}
+	if !useCache {
+		options.BrokenCacheTTL = 0
+	}
+	return options
+}
+
+func countBrokenLinks(reports []PostLinkReport) int {
+	count := 0
+	for _, report := range reports {
+		count += len(report.BrokenLinks)
+	}
+	return count
+}
+
+// writeLinkCheckReport writes the posts with broken links as "text" or "json"
+func writeLinkCheckReport(w io.Writer, reports []PostLinkReport, format string) error {
+	brokenReports := make([]PostLinkReport, 0)
+	for _, report := range reports {
+		if len(report.BrokenLinks) > 0 {
+			brokenReports = append(brokenReports, report)
+		}
+	}
+
+	if format == "json" {
+		b, err := json.MarshalIndent(brokenReports, "", "  ")
+		if err != nil {
+			log.Printf("json.MarshalIndent failed\n")
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+
+	for _, report := range brokenReports {
+		fmt.Fprintf(w, "%s (%s): %d of %d links broken\n", report.RepoName, report.PostFileName, len(report.BrokenLinks), report.LinkCount)
+		for _, result := range report.BrokenLinks {
+			fmt.Fprintf(w, "  %s - %s\n", result.URL, result.Error)
+		}
+	}
+	fmt.Fprintf(w, "%d broken links in %d of %d posts\n", countBrokenLinks(reports), len(brokenReports), len(reports))
+	return nil
+}
+
+func checkLinksForUser(username string, w io.Writer, format string) (int, error) {
+	repoPosts, err := getRepoPosts(username)
+	if err != nil {
+		log.Printf("getRepoPosts(%s) failed\n", username)
+		return 0, err
+	}
+
+	reports := checkLinks(repoPosts, getLinkCheckOptions())
+	if err := writeLinkCheckReport(w, reports, format); err != nil {
+		log.Printf("writeLinkCheckReport failed\n")
+		return 0, err
+	}
+	return countBrokenLinks(reports), nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExtractURLs(t *testing.T) {
+	markdown := "see [docs](https://example.com/docs), <https://example.com/auto> and https://example.com/bare.\n\n" +
+		"```sh\ncurl https://example.com/in-code\n```\n\n`https://example.com/inline` [again](https://example.com/docs)\n\n" +
+		"[go](https://en.wikipedia.org/wiki/Go_(programming_language)) ![logo](images/logo.png) <img src=\"https://example.com/logo.png\">\n"
+	result := extractURLs(markdown)
+	want := []string{"https://example.com/docs", "https://example.com/auto", "https://example.com/bare",
+		"https://en.wikipedia.org/wiki/Go_(programming_language)", "https://example.com/logo.png"}
+	if strings.Join(result, " ") != strings.Join(want, " ") {
+		t.Errorf("got %v, want %v", result, want)
+	}
+}
+
+func TestCheckLinks(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		if r.URL.Path == "/deleted-gist" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	repoPosts := []RepoPost{
+		{Repo: newTestRepo("a-playground"), PostFileName: "generated-a-playground.md", MarkdownBody: "[ok](" + server.URL + "/ok) [gist](" + server.URL + "/deleted-gist)"},
+		{Repo: newTestRepo("b-playground"), PostFileName: "generated-b-playground.md", MarkdownBody: "[ok](" + server.URL + "/ok)"},
+	}
+
+	interval := 20 * time.Millisecond
+	start := time.Now()
+	reports := checkLinks(repoPosts, LinkCheckOptions{Concurrency: 4, HostInterval: interval})
+	if elapsed := time.Since(start); elapsed < interval {
+		t.Errorf("expected requests to the same host to be rate limited. took %v", elapsed)
+	}
+
+	if requests["/ok"] != 1 {
+		t.Errorf("expected each link to be requested once. got %d", requests["/ok"])
+	}
+	if len(reports) != 2 || len(reports[0].BrokenLinks) != 1 || len(reports[1].BrokenLinks) != 0 {
+		t.Fatalf("unexpected reports %+v", reports)
+	}
+	if reports[0].BrokenLinks[0].URL != server.URL+"/deleted-gist" {
+		t.Errorf("got %s, want %s", reports[0].BrokenLinks[0].URL, server.URL+"/deleted-gist")
+	}
+
+	var buf bytes.Buffer
+	if err := writeLinkCheckReport(&buf, reports, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []PostLinkReport
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 1 || decoded[0].RepoName != "a-playground" {
+		t.Errorf("unexpected json report %s", buf.String())
+	}
+
+	buf.Reset()
+	writeLinkCheckReport(&buf, reports, "text")
+	if !strings.Contains(buf.String(), "1 broken links in 1 of 2 posts") {
+		t.Errorf("unexpected text report %s", buf.String())
+	}
+}
+
+func TestCheckLinkRequests(t *testing.T) {
+	var mu sync.Mutex
+	methods := make(map[string][]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods[r.URL.Path] = append(methods[r.URL.Path], r.Method+" "+r.Header.Get("Range"))
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte("o"))
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		case "/gone":
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Timeout: 50 * time.Millisecond}
+	limiter := newHostRateLimiter(0)
+	if result := checkLink(client, server.URL+"/no-head", limiter, 0); result.Broken {
+		t.Errorf("expected a ranged GET to be tried when HEAD fails, got %+v", result)
+	}
+	if strings.Join(methods["/no-head"], ",") != "HEAD ,GET bytes=0-0" {
+		t.Errorf("unexpected requests %v", methods["/no-head"])
+	}
+	if result := checkLink(client, server.URL+"/slow", limiter, 0); !result.Broken {
+		t.Errorf("expected a link that doesn't answer in time to be broken")
+	}
+	if result := checkLink(client, server.URL+"/gone", limiter, 0); !result.Broken || result.Error != "Status error: 404" {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func TestCheckLinkCache(t *testing.T) {
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		if r.URL.Path == "/ok" {
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	// the URL response cache is under tmp in the working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	client := &http.Client{Timeout: time.Second}
+	limiter := newHostRateLimiter(0)
+	for i := 0; i < 2; i++ {
+		if result := checkLink(client, server.URL+"/gone", limiter, time.Hour); !result.Broken || result.Error != "Status error: 404" {
+			t.Errorf("unexpected result %+v", result)
+		}
+		if result := checkLink(client, server.URL+"/ok", limiter, time.Hour); result.Broken {
+			t.Errorf("unexpected result %+v", result)
+		}
+	}
+	if requests["/gone"] != 2 {
+		t.Errorf("expected the broken link to be read from the cache, got %d requests", requests["/gone"])
+	}
+	if requests["/ok"] != 2 {
+		t.Errorf("expected the working link to be requested every time, got %d requests", requests["/ok"])
+	}
+
+	// an expired result is checked again
+	time.Sleep(10 * time.Millisecond)
+	checkLink(client, server.URL+"/gone", limiter, 5*time.Millisecond)
+	if requests["/gone"] != 4 {
+		t.Errorf("expected the expired link to be requested, got %d requests", requests["/gone"])
+	}
+
+	checkLink(client, server.URL+"/gone", limiter, 0)
+	if requests["/gone"] != 6 {
+		t.Errorf("expected the link to be requested without the cache, got %d requests", requests["/gone"])
+	}
+}
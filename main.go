@@ -12,11 +12,11 @@ import (
 	"io/ioutil"
 	"math/rand"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
-	"text/template"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -32,9 +32,29 @@ var path string
 var destinationDirectory string
 var useCache bool
 var debug bool
+var outputFormat string
+var target string
+var frontMatterFormat string
+var templateDirectory string
+var siteDirectory string
+var searchIndexFormat string
+var onEdited string
+var pruneAction string
+var archiveDirectory string
+var dryRun bool
+var showDiff bool
+var redirectsFormat string
+var configPath string
+var configSets ConfigOverrides
+var profile string
+var allProfiles bool
+var explainTags string
 
 const tempDirectoryName = "tmp"
 
+// readmeRef git ref that repo files are fetched from when github didn't name the repo's default branch
+const readmeRef = "master"
+
 func init() {
 	//log.SetFormatter(&log.JSONFormatter{})
 	log.SetLevel(log.DebugLevel)
@@ -44,16 +64,43 @@ func init() {
 	flag.StringVar(&destinationDirectory, "destination-directory", "", "directory to save geneated markdown post file(s) to")
 	flag.BoolVar(&useCache, "cache", true, "cache requests to repo")
 	flag.BoolVar(&debug, "debug", false, "print debug information")
+	flag.StringVar(&target, "target", hugoTarget, "site generator to write posts for (hugo, jekyll or html)")
+	flag.StringVar(&frontMatterFormat, "front-matter-format", "", "post front matter format (toml, yaml or json). defaults to the format of the target")
+	flag.StringVar(&templateDirectory, "template-dir", "", "directory of templates that override the built in templates")
+	flag.StringVar(&outputFormat, "format", "text", "report output format (text or json)")
+	flag.StringVar(&siteDirectory, "site-directory", "", "root directory of the site taxonomy data, pages and feeds are written to")
+	flag.StringVar(&searchIndexFormat, "search-index-format", documentsSearchIndexFormat, "search index format (documents or lunr)")
+	flag.StringVar(&onEdited, "on-edited", onEditedSkip, "what to do with post files edited since they were generated (overwrite, skip, sidecar or merge)")
+	flag.StringVar(&pruneAction, "prune-action", pruneActionList, "what to do with orphaned post files (list, delete or archive)")
+	flag.StringVar(&archiveDirectory, "archive-directory", "", "directory orphaned post files are moved to by -prune-action=archive")
+	flag.BoolVar(&dryRun, "dry-run", false, "report what generate-markdown-post-files would change without writing anything")
+	flag.StringVar(&redirectsFormat, "redirects-format", netlifyRedirectsFormat, "redirects file format (netlify or nginx)")
+	flag.BoolVar(&showDiff, "diff", false, "include a unified diff of every new and changed post file in the -dry-run report")
+	flag.StringVar(&configPath, "config", "", "config file (default config.yaml when it exists)")
+	flag.Var(&configSets, "set", "override a config key, e.g. -set outputs.feeds.itemLimit=10. can be repeated")
+	flag.StringVar(&profile, "profile", "", "config profile to use")
+	flag.BoolVar(&allProfiles, "all-profiles", false, "run the command once for every config profile")
+	flag.StringVar(&explainTags, "explain-tags", "", "repo name to show the tags of and the setting or tag rule behind each tag")
 }
 
 // RepoPost contents of a post created from a repo
 type RepoPost struct {
-	Repo             *github.Repository
-	Title            string
-	Summary          string
-	Slug             string
-	Tags             []string
-	MarkdownBody     string
+	Repo         *github.Repository
+	Title        string
+	Summary      string
+	Slug         string
+	Tags         []string
+	Author       Author
+	MarkdownBody string
+	// FeedMarkdownBody the body with GFM constructs as plain HTML instead of shortcodes, rendered into full content feeds
+	FeedMarkdownBody string
+	TableOfContents  []TOCEntry
+	WordCount        int
+	ReadingTime      int
+	CleanupRemovals  []CleanupRemoval
+	Aliases          []string
+	Images           []string
+	TemplateName     string
 	PostFileName     string
 	PostFileContents string
 }
@@ -126,20 +173,40 @@ func copyDirectoryRecursively(src string, dst string) error {
 }
 
 func getGithubClient() *github.Client {
+	config := getConfig()
 	ctx := context.Background()
 	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: os.Getenv("GITHUB_ACCESS_TOKEN")},
+		&oauth2.Token{AccessToken: config.GitHub.AccessToken},
 	)
 	tc := oauth2.NewClient(ctx, ts)
 
 	client := github.NewClient(tc)
+	if baseURL := config.GitHub.APIBaseURL; baseURL != "" {
+		// e.g. a github enterprise server or a test server
+		u, err := url.Parse(strings.TrimSuffix(baseURL, "/") + "/")
+		if err != nil {
+			log.Printf("url.Parse(%s) failed\n", baseURL)
+		} else {
+			client.BaseURL = u
+		}
+	}
 	return client
 }
 
+// reposByUser repo lists already read this run, shared by every profile
+var reposByUser = make(map[string][]*github.Repository)
+var reposByUserMutex sync.Mutex
+
 func getReposForUser(user string, cache bool) ([]*github.Repository, error) {
 
 	cachedReposPath := getCachedReposPathForUser(user)
 	if cache {
+		reposByUserMutex.Lock()
+		repos, ok := reposByUser[user]
+		reposByUserMutex.Unlock()
+		if ok {
+			return repos, nil
+		}
 		if fileExists(cachedReposPath) {
 			blob, _ := ioutil.ReadFile(cachedReposPath)
 			var respositoryList []*github.Repository
@@ -147,6 +214,7 @@ func getReposForUser(user string, cache bool) ([]*github.Repository, error) {
 				log.Printf("failed to unmarshall respository list")
 				return nil, err
 			}
+			setReposForUser(user, respositoryList)
 			return respositoryList, nil
 		}
 	}
@@ -187,28 +255,38 @@ func getReposForUser(user string, cache bool) ([]*github.Repository, error) {
 
 	}
 
+	setReposForUser(user, userRepos)
 	return userRepos, nil
 }
 
+func setReposForUser(user string, repos []*github.Repository) {
+	reposByUserMutex.Lock()
+	defer reposByUserMutex.Unlock()
+	reposByUser[user] = repos
+}
+
 func getFilteredRepos(repos []*github.Repository) ([]*github.Repository, error) {
 	var filteredRepos []*github.Repository
+	filters := getConfig().Filters
+	includeFilters, err := compileConfigRegexps("filters.include", filters.Include)
+	if err != nil {
+		return nil, err
+	}
+	excludeFilters, err := compileConfigRegexps("filters.exclude", filters.Exclude)
+	if err != nil {
+		return nil, err
+	}
 	for _, repo := range repos {
-		var match bool = false
-		includeFiltersString := os.Getenv("REPO_NAME_INCLUDE_FILTERS")
-		includeFilters := strings.Split(includeFiltersString, ",")
+		// without include filters every repo is included
+		var match bool = len(includeFilters) == 0
 
-		for _, includeFilter := range includeFilters {
-			re := regexp.MustCompile(includeFilter)
+		for _, re := range includeFilters {
 			if re.Match([]byte(*repo.Name)) == true {
 				match = true
 			}
 		}
 
-		excludeFiltersString := os.Getenv("REPO_NAME_EXCLUDE_FILTERS")
-		excludeFilters := strings.Split(excludeFiltersString, ",")
-
-		for _, excludeFilter := range excludeFilters {
-			re := regexp.MustCompile(excludeFilter)
+		for _, re := range excludeFilters {
 			if re.Match([]byte(*repo.Name)) == true {
 				match = false
 			}
@@ -232,17 +310,14 @@ func getPostTitle(repoName string) string {
 		return -1
 	}
 
-	// check post title override map
-	repoNameToPostTitleMappingsString := os.Getenv("REPO_NAME_TO_POST_TITLE_MAPPINGS")
-	repoNameToPostTitleMap := jsonStringToMapOfStringToString(repoNameToPostTitleMappingsString)
+	config := getConfig()
 
-	for name, title := range repoNameToPostTitleMap {
-		if repoName == name {
-			return title
-		}
+	// check post title override map
+	if title, ok := config.Titles.Mappings[repoName]; ok {
+		return title
 	}
 
-	wordsToCorrectCasing := getEnvAsArray("WORDS_TO_CORRECT_CASING_LIST")
+	wordsToCorrectCasing := config.Titles.WordCasing
 	wordsToCorrectCasingLowerCase := make([]string, 0)
 
 	for _, word := range wordsToCorrectCasing {
@@ -284,8 +359,28 @@ func getURLResponseCacheFilePath(url string) string {
 	return filepath.Join(getURLResponseCacheDirectory(), getMD5Hash(url))
 }
 
+// URLStatusError a response that wasn't 200 OK
+type URLStatusError struct {
+	StatusCode int
+}
+
+func (err *URLStatusError) Error() string {
+	return fmt.Sprintf("Status error: %v", err.StatusCode)
+}
+
+// urlResponseBodies response bodies already fetched this run, shared by every profile even when -cache=false
+var urlResponseBodies = make(map[string]string)
+var urlResponseBodiesMutex sync.Mutex
+
 func getURLResponseBody(url string, cache bool) (string, error) {
 
+	urlResponseBodiesMutex.Lock()
+	body, ok := urlResponseBodies[url]
+	urlResponseBodiesMutex.Unlock()
+	if ok {
+		return body, nil
+	}
+
 	urlResponseCacheFilePath := getURLResponseCacheFilePath(url)
 	if cache {
 		if fileExists(urlResponseCacheFilePath) {
@@ -305,7 +400,7 @@ func getURLResponseBody(url string, cache bool) (string, error) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("Status error: %v", resp.StatusCode)
+		return "", &URLStatusError{StatusCode: resp.StatusCode}
 	}
 
 	data, err := ioutil.ReadAll(resp.Body)
@@ -323,11 +418,14 @@ func getURLResponseBody(url string, cache bool) (string, error) {
 		}
 	}
 
+	urlResponseBodiesMutex.Lock()
+	urlResponseBodies[url] = string(data)
+	urlResponseBodiesMutex.Unlock()
 	return string(data), nil
 }
 
 func getPostBodyForRepo(repo *github.Repository) (string, error) {
-	url := "https://raw.githubusercontent.com/" + *repo.FullName + "/master/README.md"
+	url := getRawFileURL(repo, "README.md")
 	contents, err := getURLResponseBody(url, useCache)
 	if err != nil {
 		log.Printf("getURLContents(%s) failed", url)
@@ -348,21 +446,14 @@ func getPostFileNameForRepo(repo *github.Repository) string {
 }
 
 func randomSummaryPrefix() string {
-	randomSummaryPrefixListString := os.Getenv("RANDOM_SUMMARY_PREFIX_LIST")
+	randomSummaryPrefixList := getConfig().Summaries.Prefixes
+	if len(randomSummaryPrefixList) == 0 {
+		return ""
+	}
 	rand.Seed(time.Now().Unix())
-	randomSummaryPrefixList := strings.Split(randomSummaryPrefixListString, ",")
 	return randomSummaryPrefixList[rand.Intn(len(randomSummaryPrefixList))]
 }
 
-func getEnvAsArray(key string) []string {
-	result := []string{}
-	value := os.Getenv(key)
-	if value != "" {
-		result = strings.Split(value, ",")
-	}
-	return result
-}
-
 func arrayIntersection(a, b []string) (c []string) {
 	m := make(map[string]bool)
 
@@ -390,66 +481,20 @@ func unique(stringSlice []string) []string {
 	return list
 }
 
-func jsonStringToMapOfStringToString(jsonString string) map[string]string {
-	m := make(map[string]string)
-
-	err := json.Unmarshal([]byte(jsonString), &m)
-	if err != nil {
-		fmt.Printf("Error: %s\n", err)
-		return m
-	}
-	return m
-}
-
-func getPostTags(repo *github.Repository) []string {
-	autoTagsIfInRepoName := getEnvAsArray("AUTO_TAGS_IF_IN_REPO_NAME")
-	staticTags := getEnvAsArray("STATIC_TAGS")
-	repoNameTagMappingsString := os.Getenv("REPO_NAME_TAG_MAPPINGS")
-
-	tagMapJSON := os.Getenv("TAG_MAP_JSON")
-
-	tagMap := make(map[string]string)
-	err := json.Unmarshal([]byte(tagMapJSON), &tagMap)
+func getPostTags(repo *github.Repository, readme string) ([]string, error) {
+	explanations, err := explainPostTags(repo, readme)
 	if err != nil {
-		panic(err)
-	}
-
-	words := strings.Split(*repo.Name, "-")
-	autoTags := arrayIntersection(autoTagsIfInRepoName, words)
-
-	repoNameTagMappings := strings.Split(repoNameTagMappingsString, "|")
-
-	repoMappingTags := []string{}
-	for _, repoNameTagMapping := range repoNameTagMappings {
-		repoNameToTagsList := strings.Split(repoNameTagMapping, "=")
-		if len(repoNameToTagsList) < 2 {
-			continue
-		}
-		repoName := repoNameToTagsList[0]
-		tagsListString := repoNameToTagsList[1]
-		tags := strings.Split(tagsListString, ",")
-
-		for _, tag := range tags {
-			if repoName == *repo.Name {
-				repoMappingTags = append(repoMappingTags, tag)
-			}
-		}
-
+		log.Printf("explainPostTags(%s) failed\n", *repo.Name)
+		return nil, err
 	}
 
-	allPostTags := append(autoTags, append(repoMappingTags, staticTags...)...)
-
 	resultPostTags := make([]string, 0)
-	for _, postTag := range allPostTags {
-
-		if tagName, ok := tagMap[postTag]; ok {
-			resultPostTags = append(resultPostTags, tagName)
-		} else {
-			resultPostTags = append(resultPostTags, postTag)
+	for _, explanation := range explanations {
+		if explanation.RemovedBy == "" {
+			resultPostTags = append(resultPostTags, explanation.Tag)
 		}
 	}
-
-	return unique(resultPostTags)
+	return resultPostTags, nil
 }
 
 func getPostSlug(repo *github.Repository) string {
@@ -464,20 +509,89 @@ func newRepoPost(repo *github.Repository) (*RepoPost, error) {
 		return nil, err
 	}
 
+	tags, err := getPostTags(repo, markdownBody)
+	if err != nil {
+		log.Printf("getPostTags(%s) failed\n", *repo.Name)
+		return nil, err
+	}
+
 	lines := strings.Split(markdownBody, "\n")
 
 	markdownBody = strings.Join(lines[1:], "\n")
+	readme := markdownBody
+	markdownBody, err = sanitizePostBody(*repo.Name, markdownBody)
+	if err != nil {
+		log.Printf("sanitizePostBody(%s) failed\n", *repo.Name)
+		return nil, err
+	}
+	markdownBody, cleanupRemovals, err := cleanupPostBody(*repo.Name, markdownBody)
+	if err != nil {
+		log.Printf("cleanupPostBody(%s) failed\n", *repo.Name)
+		return nil, err
+	}
+	markdownBody = embedSourceFiles(repo, markdownBody)
+
+	gfmTransformOptions := getGFMTransformOptions()
+	feedMarkdownBody := transformGithubFlavoredMarkdown(markdownBody, gfmTransformOptions.plainHTML())
+	markdownBody = transformGithubFlavoredMarkdown(markdownBody, gfmTransformOptions)
+
+	outputTarget, err := getOutputTarget()
+	if err != nil {
+		log.Printf("getOutputTarget() failed\n")
+		return nil, err
+	}
+	if outputTarget.TransformBody != nil {
+		markdownBody = outputTarget.TransformBody(repo, markdownBody)
+	}
+
+	// counted on the transformed body. mermaid diagrams are code even once they're shortcodes
+	// and the injected table of contents only repeats the headings
+	tableOfContents := getHeadingOutline(markdownBody)
+	wordCount := countWords(markdownBody, gfmTransformOptions.shortcodeFor("mermaid"))
+	tocMode := getConfig().Body.TOCMode
+	if tocMode == tocModeInject && len(tableOfContents) > 0 {
+		markdownBody = getTableOfContentsMarkdown(tableOfContents) + "\n" + markdownBody
+	}
+	if tocMode != tocModeFrontMatter {
+		tableOfContents = nil
+	}
 
 	title := getPostTitle(*repo.Name)
 	repoPost := &RepoPost{
-		Repo:         repo,
-		Title:        title,
-		Summary:      randomSummaryPrefix() + " " + title,
-		Slug:         getPostSlug(repo),
-		Tags:         getPostTags(repo),
-		MarkdownBody: markdownBody,
-		PostFileName: getPostFileNameForRepo(repo),
+		Repo:             repo,
+		Title:            title,
+		Summary:          randomSummaryPrefix() + " " + title,
+		Slug:             getPostSlug(repo),
+		Tags:             tags,
+		Author:           getPostAuthor(repo),
+		MarkdownBody:     markdownBody,
+		FeedMarkdownBody: feedMarkdownBody,
+		TableOfContents:  tableOfContents,
+		WordCount:        wordCount,
+		ReadingTime:      getReadingTime(wordCount, getReadingWordsPerMinute()),
+		CleanupRemovals:  cleanupRemovals,
+	}
+	repoPost.PostFileName = outputTarget.PostFileName(repoPost)
+
+	slugHistory, err := loadSlugHistory(getSlugHistoryPath())
+	if err != nil {
+		log.Printf("loadSlugHistory(%s) failed\n", getSlugHistoryPath())
+		return nil, err
+	}
+	repoPost.Aliases = getPostAliases(repoPost, slugHistory, outputTarget)
+	repoPost.Images = getSocialCardImages(repoPost)
+
+	templateRules, err := getTemplateRules()
+	if err != nil {
+		log.Printf("getTemplateRules() failed\n")
+		return nil, err
 	}
+	repoPost.TemplateName, err = selectTemplate(repoPost, readme, templateRules, getDefaultTemplateName(outputTarget))
+	if err != nil {
+		log.Printf("selectTemplate(%s) failed\n", *repo.Name)
+		return nil, err
+	}
+
 	postFileContents, err := getPostFileContents(repoPost)
 	if err != nil {
 		log.Printf("getPostFileContents(%s) failed\n", *repo.Name)
@@ -489,22 +603,50 @@ func newRepoPost(repo *github.Repository) (*RepoPost, error) {
 }
 
 func getPostFileContents(repoPost *RepoPost) (string, error) {
-	postTemplatePath := filepath.Join("templates", "post.md")
+	outputTarget, err := getOutputTarget()
+	if err != nil {
+		log.Printf("getOutputTarget() failed\n")
+		return "", err
+	}
+
+	if outputTarget.RenderPost != nil {
+		return outputTarget.RenderPost(repoPost)
+	}
+
+	format, err := getFrontMatterFormat(outputTarget)
+	if err != nil {
+		log.Printf("getFrontMatterFormat(%s) failed\n", outputTarget.Name)
+		return "", err
+	}
 
-	b, err := ioutil.ReadFile(postTemplatePath)
-	contentsAsString := string(b)
+	frontMatter, err := marshalFrontMatter(outputTarget.FrontMatter(repoPost), format)
+	if err != nil {
+		log.Printf("marshalFrontMatter(%s) failed\n", *repoPost.Repo.Name)
+		return "", err
+	}
 
-	t := template.Must(template.New("hugo-markdown-post-tmpl").Parse(contentsAsString))
+	t, err := loadTemplates(templateDirectory)
+	if err != nil {
+		log.Printf("loadTemplates(%s) failed\n", templateDirectory)
+		return "", err
+	}
 
 	var buf bytes.Buffer
+	buf.WriteString(frontMatter)
+	buf.WriteString("\n")
 
-	err = t.Execute(&buf, repoPost)
+	templateName := repoPost.TemplateName
+	if templateName == "" {
+		templateName = outputTarget.TemplateName
+	}
+
+	err = t.ExecuteTemplate(&buf, templateName, repoPost)
 	if err != nil {
-		panic(err)
+		log.Printf("template.ExecuteTemplate(%s) failed\n", templateName)
+		return "", err
 	}
 
-	result := buf.String()
-	return result, nil
+	return buf.String(), nil
 }
 
 func getCachedReposPathForUser(user string) string {
@@ -539,6 +681,10 @@ func createMarkdownPostFile(repoPost RepoPost, destinationDirectory string) erro
 	}
 
 	path := filepath.Join(destinationDirectory, repoPost.PostFileName)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		log.Printf("os.MkdirAll(%s) failed\n", filepath.Dir(path))
+		return err
+	}
 	if err := ioutil.WriteFile(path, []byte(repoPost.PostFileContents), 0644); err != nil {
 		log.Printf("ioutil.WriteFile(%s) failed\n", path)
 		return err
@@ -566,9 +712,9 @@ func getFilteredReposForUser(user string) ([]*github.Repository, error) {
 func getRepoPosts(username string) ([]RepoPost, error) {
 	repoPosts := make([]RepoPost, 0)
 
-	filteredRepos, err := getFilteredReposForUser(user)
+	filteredRepos, err := getFilteredReposForUser(username)
 	if err != nil {
-		log.Printf("getFilteredReposForUser(%s) failed\n", user)
+		log.Printf("getFilteredReposForUser(%s) failed\n", username)
 		return nil, err
 	}
 
@@ -625,35 +771,211 @@ func createMarkdownPostFiles(username string, destinationDirectory string) error
 		return nil
 	}
 
-	for _, repoPost := range repoPosts {
-		if debug {
-			log.Printf("createMarkdownPostFile(%s, \"%s\")\n", *repoPost.Repo.Name, destinationDirectory)
+	if dryRun {
+		report, err := getDryRunReport(repoPosts, destinationDirectory, showDiff)
+		if err != nil {
+			log.Printf("getDryRunReport(%s) failed\n", destinationDirectory)
+			return err
 		}
-		err := createMarkdownPostFile(repoPost, destinationDirectory)
+		return writeDryRunReport(os.Stdout, report, outputFormat)
+	}
+
+	if _, err := writePostFiles(repoPosts, destinationDirectory); err != nil {
+		return err
+	}
+	return recordPublishedSlugs(repoPosts)
+}
+
+// writePostFiles writes every post file, keeping hand edits as -on-edited says, then the rest of the site for targets that have one
+func writePostFiles(repoPosts []RepoPost, destinationDirectory string) ([]PostFileResult, error) {
+	results := make([]PostFileResult, 0, len(repoPosts))
+	state, err := loadGeneratedPostsState(destinationDirectory)
+	if err != nil {
+		log.Printf("loadGeneratedPostsState(%s) failed\n", destinationDirectory)
+		return nil, err
+	}
+
+	for _, repoPost := range repoPosts {
+		log.Printf("createMarkdownPostFile(%s, \"%s\") template: %s\n", *repoPost.Repo.Name, destinationDirectory, repoPost.TemplateName)
+		result, err := writeGeneratedPostFile(repoPost, destinationDirectory, state, onEdited)
 		if err != nil {
 			log.Printf("generateMarkdownPostFile(%s) failed\n", *repoPost.Repo.Name)
 			//return err
+			continue
+		}
+		if result.Action != postFileCreated && result.Action != postFileUpdated && result.Action != postFileUnchanged {
+			log.Printf("%s was edited since it was generated: %s\n", result.PostFileName, result.Action)
+		}
+		for _, conflict := range result.Conflicts {
+			log.Warnf("%s merge conflict: %s\n", result.PostFileName, conflict)
 		}
+		results = append(results, result)
 	}
 
-	return nil
+	if err := writeSocialCards(repoPosts, destinationDirectory, state); err != nil {
+		log.Printf("writeSocialCards(%s) failed\n", destinationDirectory)
+		return results, err
+	}
+
+	if err := saveGeneratedPostsState(destinationDirectory, state); err != nil {
+		log.Printf("saveGeneratedPostsState(%s) failed\n", destinationDirectory)
+		return results, err
+	}
+
+	outputTarget, err := getOutputTarget()
+	if err != nil {
+		log.Printf("getOutputTarget() failed\n")
+		return results, err
+	}
+	if outputTarget.WriteSite != nil {
+		if err := outputTarget.WriteSite(repoPosts, destinationDirectory); err != nil {
+			log.Printf("WriteSite(%s) failed\n", destinationDirectory)
+			return results, err
+		}
+	}
+
+	return results, nil
 }
 
 func main() {
 	flag.Parse()
+	flag.Visit(func(f *flag.Flag) { givenFlags[f.Name] = true })
+
+	if !allProfiles {
+		exitCode, err := runCommand()
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Exit(exitCode)
+	}
+	if profile != "" {
+		log.Fatal("-profile and -all-profiles can't be used together")
+	}
+	profileNames, err := getConfigProfileNames()
+	if err != nil {
+		log.Fatal(err)
+	}
+	os.Exit(runProfiles(profileNames))
+}
+
+// runProfiles runs -command once for every profile and returns the highest exit code.
+// a profile that fails is logged and the rest still run
+func runProfiles(profileNames []string) int {
+	defer func(name string) { profile = name }(profile)
+
+	// the repo list and URL response caches are shared by every profile
+	exitCode := 0
+	for _, profileName := range profileNames {
+		profile = profileName
+		log.Printf("profile: %s\n", profile)
+		code, err := runCommand()
+		if err != nil {
+			log.Errorf("profile %s failed: %v\n", profile, err)
+			code = 1
+		}
+		if code > exitCode {
+			exitCode = code
+		}
+	}
+	return exitCode
+}
+
+// runCommand runs -command with the config of -profile and returns the exit code, or the error the command failed with
+func runCommand() (int, error) {
+	_, problems := checkConfig()
+	if command == "validate-config" {
+		if err := writeConfigProblems(os.Stdout, problems, outputFormat); err != nil {
+			return 1, err
+		}
+		if countConfigProblems(problems, configProblemError) > 0 {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	for _, problem := range problems {
+		if problem.Severity == configProblemError {
+			log.Error(problem)
+		} else {
+			log.Warn(problem)
+		}
+	}
+	if errorCount := countConfigProblems(problems, configProblemError); errorCount > 0 {
+		return 1, fmt.Errorf("%d config errors", errorCount)
+	}
 
 	if command == "fetch-and-save-repos-for-user" {
 		log.Printf("command: %s, user: %s, path: %s\n", command, user, path)
 		if err := getAndSaveReposForUser(user, path); err != nil {
-			log.Fatal(err)
+			return 1, err
 		}
 	}
 
 	if command == "generate-markdown-post-files" {
-		log.Printf("command: %s, user: %s, destinationDirectory: %s\n", command, user, destinationDirectory)
+		log.Printf("command: %s, user: %s, destinationDirectory: %s, target: %s\n", command, user, destinationDirectory, target)
 		if err := createMarkdownPostFiles(user, destinationDirectory); err != nil {
-			log.Fatal(err)
+			return 1, err
+		}
+	}
+
+	if command == "check-links" {
+		log.Printf("command: %s, user: %s, format: %s\n", command, user, outputFormat)
+		brokenLinkCount, err := checkLinksForUser(user, os.Stdout, outputFormat)
+		if err != nil {
+			return 1, err
+		}
+		if brokenLinkCount > 0 {
+			return 1, nil
+		}
+	}
+
+	if command == "prune" {
+		log.Printf("command: %s, user: %s, destinationDirectory: %s, pruneAction: %s\n", command, user, destinationDirectory, pruneAction)
+		if err := prunePostFilesForUser(user, destinationDirectory, pruneAction, archiveDirectory, os.Stdout, outputFormat); err != nil {
+			return 1, err
+		}
+	}
+
+	if command == "publish" {
+		log.Printf("command: %s, user: %s, target: %s\n", command, user, target)
+		if err := publishPostsForUser(user); err != nil {
+			return 1, err
+		}
+	}
+
+	if command == "generate-redirects" {
+		log.Printf("command: %s, user: %s, path: %s, redirectsFormat: %s\n", command, user, path, redirectsFormat)
+		if err := createRedirectsForUser(user, path, redirectsFormat); err != nil {
+			return 1, err
+		}
+	}
+
+	if command == "generate-taxonomy" {
+		log.Printf("command: %s, user: %s, siteDirectory: %s\n", command, user, siteDirectory)
+		if err := createTaxonomyFilesForUser(user, siteDirectory); err != nil {
+			return 1, err
+		}
+	}
+
+	if command == "generate-search-index" {
+		log.Printf("command: %s, user: %s, path: %s, searchIndexFormat: %s\n", command, user, path, searchIndexFormat)
+		if err := createSearchIndexForUser(user, path, searchIndexFormat); err != nil {
+			return 1, err
+		}
+	}
+
+	if command == "generate-feeds" {
+		log.Printf("command: %s, user: %s, siteDirectory: %s\n", command, user, siteDirectory)
+		if err := createFeedsForUser(user, siteDirectory); err != nil {
+			return 1, err
+		}
+	}
+
+	if explainTags != "" {
+		log.Printf("explainTags: %s, user: %s, format: %s\n", explainTags, user, outputFormat)
+		if err := explainPostTagsForUser(user, explainTags, os.Stdout, outputFormat, useCache); err != nil {
+			return 1, err
 		}
 	}
 
+	return 0, nil
 }
@@ -3,6 +3,8 @@ package main
 import (
 	"encoding/json"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
@@ -15,7 +17,7 @@ import (
 )
 
 type ExpectedTestResults struct {
-	Title string `json: "title"`
+	Title string `json:"title"`
 }
 
 var testdataDirectoryName string
@@ -26,6 +28,19 @@ func init() {
 	githubUsername = os.Getenv("GITHUB_USERNAME")
 }
 
+func newTestRepo(name string) *github.Repository {
+	fullName := "pfeilbr/" + name
+	htmlURL := "https://github.com/" + fullName
+	return &github.Repository{Name: &name, FullName: &fullName, HTMLURL: &htmlURL}
+}
+
+// newTestRepoPost a post of newTestRepo(name) with the slug and post file name the repo gets by default
+func newTestRepoPost(name string, title string, markdownBody string, tags ...string) RepoPost {
+	repo := newTestRepo(name)
+	return RepoPost{Repo: repo, Title: title, Slug: name, Summary: "learn " + title, Tags: tags,
+		MarkdownBody: markdownBody, FeedMarkdownBody: markdownBody, PostFileName: getPostFileNameForRepo(repo)}
+}
+
 func Map(vs []*github.Repository, f func(*github.Repository) string) []string {
 	vsm := make([]string, len(vs))
 	for i, v := range vs {
@@ -196,3 +211,47 @@ func TestGetRepoPostsWithNoTags(t *testing.T) {
 func TestLogging(t *testing.T) {
 	log.Printf("hello %s", "world")
 }
+
+func TestRunProfilesContinuesAfterFailure(t *testing.T) {
+	withTestConfig(t, "profiles:\n  a:\n    filters:\n      include: [\"(\"]\n  b:\n    github:\n      user: b-user\n")
+	defer func(name string) { user = name }(user)
+
+	if exitCode := runProfiles([]string{"a", "b"}); exitCode != 1 {
+		t.Errorf("expected exit code 1, got %d", exitCode)
+	}
+	if loadedConfig == nil || loadedConfig.GitHub.User != "b-user" {
+		t.Errorf("expected profile b to run after profile a failed, got %+v", loadedConfig)
+	}
+	if profile != "" {
+		t.Errorf("expected the profile to be restored, got %q", profile)
+	}
+}
+
+func TestNewRepoPostCountsTransformedBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pfeilbr/mermaid-playground/master/README.md":
+			w.Write([]byte("# mermaid-playground\n\n## Setup\n\none two\n\n```mermaid\ngraph TD\nA-->B\n```\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	defer func(baseURL string, cache bool) {
+		rawContentBaseURL, useCache = baseURL, cache
+	}(rawContentBaseURL, useCache)
+	rawContentBaseURL, useCache = server.URL, false
+	withTestConfig(t, "github:\n  apiBaseURL: "+server.URL+"\nbody:\n  gfmTransforms: [mermaid]\n  tocMode: front-matter\noutputs:\n  slugHistoryFile: "+filepath.Join(t.TempDir(), "slug-history.json")+"\n")
+
+	repoPost, err := newRepoPost(newTestRepo("mermaid-playground"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(repoPost.MarkdownBody, "{{< mermaid >}}") {
+		t.Errorf("expected the mermaid shortcode in %q", repoPost.MarkdownBody)
+	}
+	if repoPost.WordCount != 3 || len(repoPost.TableOfContents) != 1 {
+		t.Errorf("expected 3 words and 1 heading, got %d and %v", repoPost.WordCount, repoPost.TableOfContents)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MarkdownSegment a run of markdown lines that is either inside or outside a fenced code block
+type MarkdownSegment struct {
+	Text     string
+	Fenced   bool
+	Info     string
+	Fence    string
+	Contents string
+}
+
+var fenceOpenRegexp = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})\\s*([^`]*)$")
+
+// splitMarkdownFences splits markdown into fenced code blocks and the text between them.
+// Joining the Text of every segment returns the original markdown.
+func splitMarkdownFences(markdown string) []MarkdownSegment {
+	segments := make([]MarkdownSegment, 0)
+	lines := strings.SplitAfter(markdown, "\n")
+
+	var text strings.Builder
+	flushText := func() {
+		if text.Len() > 0 {
+			segments = append(segments, MarkdownSegment{Text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		match := fenceOpenRegexp.FindStringSubmatch(strings.TrimRight(line, "\r\n"))
+		if match == nil {
+			text.WriteString(line)
+			continue
+		}
+
+		flushText()
+		fence := match[1]
+		segment := MarkdownSegment{
+			Fenced: true,
+			Fence:  fence,
+			Info:   strings.TrimSpace(match[2]),
+		}
+
+		var block strings.Builder
+		var contents strings.Builder
+		block.WriteString(line)
+		for i++; i < len(lines); i++ {
+			block.WriteString(lines[i])
+			closing := strings.TrimSpace(lines[i])
+			if strings.HasPrefix(closing, fence[:3]) && strings.Trim(closing, fence[:1]) == "" && len(closing) >= len(fence) {
+				break
+			}
+			contents.WriteString(lines[i])
+		}
+		segment.Text = block.String()
+		segment.Contents = contents.String()
+		segments = append(segments, segment)
+	}
+	flushText()
+
+	return segments
+}
+
+// mapMarkdownText applies fn to every part of markdown that is outside a fenced code block
+func mapMarkdownText(markdown string, fn func(text string) string) string {
+	var result strings.Builder
+	for _, segment := range splitMarkdownFences(markdown) {
+		if segment.Fenced {
+			result.WriteString(segment.Text)
+		} else {
+			result.WriteString(fn(segment.Text))
+		}
+	}
+	return result.String()
+}
+
+func getFenceLanguage(info string) string {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(fields[0])
+}
+
+// mapMarkdownInlineText applies fn to the parts of text that are not inline code spans
+func mapMarkdownInlineText(text string, fn func(text string) string) string {
+	var result strings.Builder
+	for {
+		start := strings.Index(text, "`")
+		if start == -1 {
+			result.WriteString(fn(text))
+			break
+		}
+		ticks := len(text[start:]) - len(strings.TrimLeft(text[start:], "`"))
+		delimiter := text[start : start+ticks]
+		end := strings.Index(text[start+ticks:], delimiter)
+		if end == -1 {
+			result.WriteString(fn(text))
+			break
+		}
+		end += start + ticks*2
+		result.WriteString(fn(text[:start]))
+		result.WriteString(text[start:end])
+		text = text[end:]
+	}
+	return result.String()
+}
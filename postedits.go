@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultGeneratedPostsStateFileName state kept in the destination directory between runs. site generators skip dot files
+const defaultGeneratedPostsStateFileName = ".generated-posts.json"
+
+const onEditedOverwrite = "overwrite"
+const onEditedSkip = "skip"
+const onEditedSidecar = "sidecar"
+const onEditedMerge = "merge"
+
+const sidecarFileExtension = ".new"
+
+// GeneratedPostsState the last generated contents of every post file keyed by its path in the destination directory
+type GeneratedPostsState struct {
+	Files map[string]GeneratedFile `json:"files"`
+}
+
+// GeneratedFile a post file as it was last generated. Base is the common ancestor of hand edits and the next generated contents
+type GeneratedFile struct {
+	Repo   string `json:"repo"`
+	SHA256 string `json:"sha256"`
+	Base   string `json:"base"`
+}
+
+// PostFileResult what happened to a post file
+type PostFileResult struct {
+	PostFileName string
+	Action       string
+	Conflicts    []string
+}
+
+const postFileCreated = "created"
+const postFileUpdated = "updated"
+const postFileUnchanged = "unchanged"
+const postFileSkipped = "skipped"
+const postFileSidecar = "sidecar"
+const postFileMerged = "merged"
+const postFileConflict = "conflict"
+
+func getContentHash(contents string) string {
+	sum := sha256.Sum256([]byte(contents))
+	return hex.EncodeToString(sum[:])
+}
+
+// getGeneratedPostsStatePath the state is kept with the posts in destinationDirectory, one file per profile,
+// unless outputs.generatedPostsStateFile says otherwise. e.g. .generated-posts-blog.json for -profile blog
+func getGeneratedPostsStatePath(destinationDirectory string) string {
+	if path := getConfig().Outputs.GeneratedPostsStateFile; path != "" {
+		return path
+	}
+	name := defaultGeneratedPostsStateFileName
+	if profile != "" {
+		name = strings.TrimSuffix(name, ".json") + "-" + profile + ".json"
+	}
+	return filepath.Join(destinationDirectory, name)
+}
+
+func loadGeneratedPostsState(destinationDirectory string) (*GeneratedPostsState, error) {
+	state := &GeneratedPostsState{Files: make(map[string]GeneratedFile)}
+	path := getGeneratedPostsStatePath(destinationDirectory)
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if state.Files == nil {
+		state.Files = make(map[string]GeneratedFile)
+	}
+	return state, nil
+}
+
+func saveGeneratedPostsState(destinationDirectory string, state *GeneratedPostsState) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := getGeneratedPostsStatePath(destinationDirectory)
+	return writeSiteFile(filepath.Dir(path), filepath.Base(path), append(b, '\n'))
+}
+
+func (state *GeneratedPostsState) record(repoPost RepoPost) {
+	state.Files[filepath.ToSlash(repoPost.PostFileName)] = GeneratedFile{
+		Repo:   repoPost.Repo.GetFullName(),
+		SHA256: getContentHash(repoPost.PostFileContents),
+		Base:   repoPost.PostFileContents,
+	}
+}
+
+// isEdited whether contents differ from what was last generated for the post file.
+// a file the state has no record of, e.g. one generated before there was a state, is generator output
+// when its front matter names the post's repo and counts as edited otherwise
+func (state *GeneratedPostsState) isEdited(repoPost RepoPost, contents string) bool {
+	generated, ok := state.Files[filepath.ToSlash(repoPost.PostFileName)]
+	if !ok {
+		return !isGeneratedPostFile(repoPost, contents)
+	}
+	return getContentHash(contents) != generated.SHA256
+}
+
+// isGeneratedPostFile whether contents have front matter naming the repo of repoPost, like every generated post file
+func isGeneratedPostFile(repoPost RepoPost, contents string) bool {
+	format, frontMatter, _ := splitFrontMatter(contents)
+	if format == "" {
+		return false
+	}
+	values, err := unmarshalFrontMatter(frontMatter, format)
+	if err != nil {
+		return false
+	}
+	repoFullName, _ := values["repoFullName"].(string)
+	return repoFullName != "" && repoFullName == repoPost.Repo.GetFullName()
+}
+
+// splitFrontMatter the front matter format, front matter and the rest of a post file.
+// format is empty when the file doesn't start with front matter
+func splitFrontMatter(contents string) (format string, frontMatter string, body string) {
+	for _, delimited := range []struct{ format, delimiter string }{{tomlFrontMatterFormat, "+++\n"}, {yamlFrontMatterFormat, "---\n"}} {
+		if !strings.HasPrefix(contents, delimited.delimiter) {
+			continue
+		}
+		end := strings.Index(contents[len(delimited.delimiter):], "\n"+delimited.delimiter)
+		if end == -1 {
+			return "", "", contents
+		}
+		end += len(delimited.delimiter) + 1 + len(delimited.delimiter)
+		return delimited.format, contents[:end], contents[end:]
+	}
+
+	if strings.HasPrefix(contents, "{") {
+		decoder := json.NewDecoder(strings.NewReader(contents))
+		var v map[string]interface{}
+		if err := decoder.Decode(&v); err == nil {
+			end := int(decoder.InputOffset())
+			if end < len(contents) && contents[end] == '\n' {
+				end++
+			}
+			return jsonFrontMatterFormat, contents[:end], contents[end:]
+		}
+	}
+	return "", "", contents
+}
+
+func unmarshalFrontMatter(frontMatter string, format string) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	switch format {
+	case tomlFrontMatterFormat:
+		_, err := toml.Decode(strings.TrimSuffix(strings.TrimPrefix(frontMatter, "+++\n"), "+++\n"), &values)
+		return values, err
+	case yamlFrontMatterFormat:
+		var document yaml.Node
+		if err := yaml.Unmarshal([]byte(strings.TrimSuffix(strings.TrimPrefix(frontMatter, "---\n"), "---\n")), &document); err != nil || len(document.Content) == 0 {
+			return values, err
+		}
+		if err := document.Decode(&values); err != nil {
+			return values, err
+		}
+		// dates like date: 2019-09-10 stay dates instead of becoming timestamps when the front matter is written again
+		mapping := document.Content[0]
+		for i := 0; i+1 < len(mapping.Content); i += 2 {
+			value := mapping.Content[i+1]
+			if value.Kind == yaml.ScalarNode && value.ShortTag() == "!!timestamp" && len(value.Value) == len(postDateFormat) {
+				if date, err := parsePostDate(value.Value); err == nil {
+					values[mapping.Content[i].Value] = date
+				}
+			}
+		}
+		return values, nil
+	case jsonFrontMatterFormat:
+		decoder := json.NewDecoder(bytes.NewReader([]byte(frontMatter)))
+		decoder.UseNumber()
+		err := decoder.Decode(&values)
+		return values, err
+	}
+	return nil, fmt.Errorf("unknown front matter format %q", format)
+}
+
+// mergeFrontMatter three way merges front matter key by key. keys both sides changed differently keep the value on disk
+func mergeFrontMatter(base, ours, theirs map[string]interface{}) (map[string]interface{}, []string) {
+	keys := make(map[string]bool)
+	for _, values := range []map[string]interface{}{base, ours, theirs} {
+		for key := range values {
+			keys[key] = true
+		}
+	}
+
+	merged := make(map[string]interface{})
+	conflicts := make([]string, 0)
+	for key := range keys {
+		baseValue, inBase := base[key]
+		oursValue, inOurs := ours[key]
+		theirsValue, inTheirs := theirs[key]
+		oursChanged := inOurs != inBase || !reflect.DeepEqual(oursValue, baseValue)
+		theirsChanged := inTheirs != inBase || !reflect.DeepEqual(theirsValue, baseValue)
+
+		value, present := oursValue, inOurs
+		if theirsChanged && !oursChanged {
+			value, present = theirsValue, inTheirs
+		}
+		if oursChanged && theirsChanged && (inOurs != inTheirs || !reflect.DeepEqual(oursValue, theirsValue)) {
+			conflicts = append(conflicts, "front matter "+key)
+		}
+		if present {
+			merged[key] = value
+		}
+	}
+	sort.Strings(conflicts)
+	return merged, conflicts
+}
+
+// mergePostFile three way merges a post file. base is the last generated contents, ours the file on disk
+// and theirs the newly generated contents. the front matter is merged by key and the body by line
+func mergePostFile(base, ours, theirs string) (string, []string, error) {
+	baseFormat, baseFrontMatter, baseBody := splitFrontMatter(base)
+	oursFormat, oursFrontMatter, oursBody := splitFrontMatter(ours)
+	theirsFormat, theirsFrontMatter, theirsBody := splitFrontMatter(theirs)
+
+	conflicts := make([]string, 0)
+	frontMatter := oursFrontMatter
+	if oursFormat != baseFormat || theirsFormat != baseFormat {
+		// the front matter format changed so fall back to merging the whole file by line
+		baseBody, oursBody, theirsBody = base, ours, theirs
+		frontMatter = ""
+	} else if baseFormat != "" {
+		values := make([]map[string]interface{}, 0, 3)
+		for _, fm := range []string{baseFrontMatter, oursFrontMatter, theirsFrontMatter} {
+			v, err := unmarshalFrontMatter(fm, baseFormat)
+			if err != nil {
+				return "", nil, err
+			}
+			values = append(values, v)
+		}
+		merged, frontMatterConflicts := mergeFrontMatter(values[0], values[1], values[2])
+		conflicts = append(conflicts, frontMatterConflicts...)
+		if !reflect.DeepEqual(merged, values[1]) {
+			var err error
+			if frontMatter, err = marshalFrontMatter(merged, baseFormat); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+
+	body, bodyConflicts := mergeLines(splitLines(baseBody), splitLines(oursBody), splitLines(theirsBody))
+	for _, conflict := range bodyConflicts {
+		conflicts = append(conflicts, "body "+conflict.String())
+	}
+	return frontMatter + strings.Join(body, ""), conflicts, nil
+}
+
+func writeSidecarFile(repoPost RepoPost, destinationDirectory string, contents string) error {
+	path := filepath.Join(destinationDirectory, repoPost.PostFileName) + sidecarFileExtension
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		log.Printf("ioutil.WriteFile(%s) failed\n", path)
+		return err
+	}
+	return nil
+}
+
+// writeGeneratedPostFile writes a post file unless it was edited since it was last generated, in which case onEdited decides:
+// overwrite the edits, skip the file, write the new contents to a .new sidecar file or merge the new contents into the edits.
+// a merge with conflicts writes the merged contents with conflict markers to the sidecar file and leaves the post file alone.
+// an edited file with no recorded contents to merge from gets the sidecar file
+func writeGeneratedPostFile(repoPost RepoPost, destinationDirectory string, state *GeneratedPostsState, onEdited string) (PostFileResult, error) {
+	result := PostFileResult{PostFileName: repoPost.PostFileName, Conflicts: make([]string, 0)}
+
+	b, err := ioutil.ReadFile(filepath.Join(destinationDirectory, repoPost.PostFileName))
+	if os.IsNotExist(err) {
+		result.Action = postFileCreated
+		if err := createMarkdownPostFile(repoPost, destinationDirectory); err != nil {
+			return result, err
+		}
+		state.record(repoPost)
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+	onDisk := string(b)
+
+	if onDisk == repoPost.PostFileContents {
+		result.Action = postFileUnchanged
+		state.record(repoPost)
+		return result, nil
+	}
+
+	generated, ok := state.Files[filepath.ToSlash(repoPost.PostFileName)]
+	if !state.isEdited(repoPost, onDisk) || onEdited == onEditedOverwrite {
+		if !ok {
+			log.Printf("adopting %s, it has no recorded contents\n", repoPost.PostFileName)
+		}
+		result.Action = postFileUpdated
+		if err := createMarkdownPostFile(repoPost, destinationDirectory); err != nil {
+			return result, err
+		}
+		state.record(repoPost)
+		return result, nil
+	}
+
+	if ok && generated.Base == repoPost.PostFileContents {
+		// nothing new to bring into the edited file
+		result.Action = postFileUnchanged
+		return result, nil
+	}
+
+	if onEdited == onEditedMerge && !ok {
+		// there's no base to merge from
+		onEdited = onEditedSidecar
+	}
+
+	switch onEdited {
+	case onEditedSkip:
+		result.Action = postFileSkipped
+	case onEditedSidecar:
+		result.Action = postFileSidecar
+		err = writeSidecarFile(repoPost, destinationDirectory, repoPost.PostFileContents)
+	case onEditedMerge:
+		var merged string
+		merged, result.Conflicts, err = mergePostFile(generated.Base, onDisk, repoPost.PostFileContents)
+		if err != nil {
+			log.Printf("mergePostFile(%s) failed\n", repoPost.PostFileName)
+			return result, err
+		}
+		if len(result.Conflicts) > 0 {
+			result.Action = postFileConflict
+			err = writeSidecarFile(repoPost, destinationDirectory, merged)
+			break
+		}
+		result.Action = postFileMerged
+		mergedPost := repoPost
+		mergedPost.PostFileContents = merged
+		if err = createMarkdownPostFile(mergedPost, destinationDirectory); err == nil {
+			state.record(repoPost)
+		}
+	default:
+		err = fmt.Errorf("unknown -on-edited %q", onEdited)
+	}
+	return result, err
+}
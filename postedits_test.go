@@ -0,0 +1,177 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMergePostFile(t *testing.T) {
+	base := "+++\ntags = [\"aws\"]\ntitle = \"Lambda\"\n+++\n\n## Usage\n\nrun it\n"
+	ours := "+++\ntags = [\"aws\"]\ntitle = \"AWS Lambda\"\n+++\n\n## Usage\n\nrun it\n\nmy notes\n"
+	theirs := "+++\ntags = [\"aws\", \"serverless\"]\ntitle = \"Lambda\"\n+++\n\n## Usage\n\nrun it with sam\n"
+
+	merged, conflicts, err := mergePostFile(base, ours, theirs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("unexpected conflicts %v", conflicts)
+	}
+	for _, want := range []string{`title = "AWS Lambda"`, `tags = ["aws", "serverless"]`, "run it with sam\n\nmy notes\n"} {
+		if !strings.Contains(merged, want) {
+			t.Errorf("expected %q in %s", want, merged)
+		}
+	}
+
+	_, conflicts, err = mergePostFile(base, ours, strings.Replace(theirs, `title = "Lambda"`, `title = "Lambda Functions"`, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) != 1 || conflicts[0] != "front matter title" {
+		t.Errorf("expected a title conflict, got %v", conflicts)
+	}
+
+	yamlBase := "---\ndate: 2019-09-10\ntitle: Lambda\n---\nrun it\n"
+	merged, _, err = mergePostFile(yamlBase, yamlBase+"my notes\n", strings.Replace(yamlBase, "title: Lambda", "title: AWS Lambda", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged != "---\ndate: 2019-09-10\ntitle: AWS Lambda\n---\nrun it\nmy notes\n" {
+		t.Errorf("expected the date to keep its format, got %q", merged)
+	}
+}
+
+func TestWriteGeneratedPostFile(t *testing.T) {
+	destinationDirectory, err := ioutil.TempDir("", "posts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(destinationDirectory)
+
+	path := filepath.Join(destinationDirectory, "generated-lambda-playground.md")
+	read := func(name string) string {
+		b, _ := ioutil.ReadFile(name)
+		return string(b)
+	}
+	repoPost := func(contents string) RepoPost {
+		return RepoPost{Repo: newTestRepo("lambda-playground"), PostFileName: "generated-lambda-playground.md", PostFileContents: contents}
+	}
+
+	state := &GeneratedPostsState{Files: make(map[string]GeneratedFile)}
+	if result, err := writeGeneratedPostFile(repoPost("one\ntwo\n"), destinationDirectory, state, onEditedSkip); err != nil || result.Action != postFileCreated {
+		t.Fatalf("expected created, got %v %v", result, err)
+	}
+
+	// hand edit
+	ioutil.WriteFile(path, []byte("one\ntwo\nnotes\n"), 0644)
+
+	if result, _ := writeGeneratedPostFile(repoPost("ONE\ntwo\n"), destinationDirectory, state, onEditedSkip); result.Action != postFileSkipped || read(path) != "one\ntwo\nnotes\n" {
+		t.Errorf("expected skipped, got %v %q", result, read(path))
+	}
+
+	if result, _ := writeGeneratedPostFile(repoPost("ONE\ntwo\n"), destinationDirectory, state, onEditedSidecar); result.Action != postFileSidecar || read(path+sidecarFileExtension) != "ONE\ntwo\n" {
+		t.Errorf("expected sidecar, got %v %q", result, read(path+sidecarFileExtension))
+	}
+
+	if result, _ := writeGeneratedPostFile(repoPost("ONE\ntwo\n"), destinationDirectory, state, onEditedMerge); result.Action != postFileMerged || read(path) != "ONE\ntwo\nnotes\n" {
+		t.Errorf("expected merged, got %v %q", result, read(path))
+	}
+
+	// edits are still detected after a merge
+	if result, _ := writeGeneratedPostFile(repoPost("ONE\nTWO\n"), destinationDirectory, state, onEditedMerge); result.Action != postFileMerged || read(path) != "ONE\nTWO\nnotes\n" {
+		t.Errorf("expected merged, got %v %q", result, read(path))
+	}
+
+	if result, _ := writeGeneratedPostFile(repoPost("ONE\nTWO\nthree\n"), destinationDirectory, state, onEditedMerge); result.Action != postFileConflict || read(path) != "ONE\nTWO\nnotes\n" {
+		t.Errorf("expected conflict, got %v %q", result, read(path))
+	}
+
+	if result, _ := writeGeneratedPostFile(repoPost("ONE\nTWO\nthree\n"), destinationDirectory, state, onEditedOverwrite); result.Action != postFileUpdated || read(path) != "ONE\nTWO\nthree\n" {
+		t.Errorf("expected updated, got %v %q", result, read(path))
+	}
+
+	if err := saveGeneratedPostsState(destinationDirectory, state); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadGeneratedPostsState(destinationDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Files["generated-lambda-playground.md"].Base != "ONE\nTWO\nthree\n" {
+		t.Errorf("unexpected state %+v", loaded)
+	}
+}
+
+func TestWriteGeneratedPostFileWithoutState(t *testing.T) {
+	destinationDirectory := t.TempDir()
+	path := filepath.Join(destinationDirectory, "generated-lambda-playground.md")
+	ioutil.WriteFile(path, []byte("one\nnotes\n"), 0644)
+	repoPost := RepoPost{Repo: newTestRepo("lambda-playground"), PostFileName: "generated-lambda-playground.md", PostFileContents: "one\n"}
+
+	// e.g. the state file was deleted, the file on disk can't be told apart from a hand edit
+	state := &GeneratedPostsState{Files: make(map[string]GeneratedFile)}
+	if result, _ := writeGeneratedPostFile(repoPost, destinationDirectory, state, onEditedSkip); result.Action != postFileSkipped {
+		t.Errorf("expected skipped, got %v", result)
+	}
+	if result, _ := writeGeneratedPostFile(repoPost, destinationDirectory, state, onEditedMerge); result.Action != postFileSidecar {
+		t.Errorf("expected sidecar, got %v", result)
+	}
+	b, _ := ioutil.ReadFile(path)
+	if string(b) != "one\nnotes\n" {
+		t.Errorf("expected the file to be left alone, got %q", b)
+	}
+}
+
+func TestGetGeneratedPostsStatePath(t *testing.T) {
+	withTestConfig(t, "")
+	destinationDirectory := t.TempDir()
+	if path := getGeneratedPostsStatePath(destinationDirectory); path != filepath.Join(destinationDirectory, ".generated-posts.json") {
+		t.Errorf("expected the state in the destination directory, got %s", path)
+	}
+
+	defer func() { profile = "" }()
+	profile = "team"
+	if path := getGeneratedPostsStatePath(destinationDirectory); path != filepath.Join(destinationDirectory, ".generated-posts-team.json") {
+		t.Errorf("expected the state to be kept per profile, got %s", path)
+	}
+
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	withTestConfig(t, "outputs:\n  generatedPostsStateFile: "+statePath+"\n")
+	if path := getGeneratedPostsStatePath(destinationDirectory); path != statePath {
+		t.Errorf("expected outputs.generatedPostsStateFile, got %s", path)
+	}
+}
+
+func TestWriteGeneratedPostFileAdoptsExistingPosts(t *testing.T) {
+	// a site generated before there was a state file
+	destinationDirectory := t.TempDir()
+	path := filepath.Join(destinationDirectory, "generated-lambda-playground.md")
+	ioutil.WriteFile(path, []byte("+++\nrepoFullName = \"pfeilbr/lambda-playground\"\ntitle = \"Lambda\"\n+++\nold\n"), 0644)
+	other := filepath.Join(destinationDirectory, "generated-other-playground.md")
+	ioutil.WriteFile(other, []byte("+++\nrepoFullName = \"pfeilbr/lambda-playground\"\n+++\nold\n"), 0644)
+
+	state, err := loadGeneratedPostsState(destinationDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := "+++\nrepoFullName = \"pfeilbr/lambda-playground\"\ntitle = \"AWS Lambda\"\n+++\nnew\n"
+	repoPost := RepoPost{Repo: newTestRepo("lambda-playground"), PostFileName: "generated-lambda-playground.md", PostFileContents: contents}
+	if result, _ := writeGeneratedPostFile(repoPost, destinationDirectory, state, onEditedSkip); result.Action != postFileUpdated {
+		t.Errorf("expected the post to be adopted and updated, got %v", result)
+	}
+	if b, _ := ioutil.ReadFile(path); string(b) != contents {
+		t.Errorf("expected the new contents, got %q", b)
+	}
+	if _, ok := state.Files["generated-lambda-playground.md"]; !ok {
+		t.Errorf("expected the post to be recorded, got %v", state.Files)
+	}
+
+	// front matter of another repo isn't this post's generator output
+	otherPost := RepoPost{Repo: newTestRepo("other-playground"), PostFileName: "generated-other-playground.md", PostFileContents: "new\n"}
+	if result, _ := writeGeneratedPostFile(otherPost, destinationDirectory, state, onEditedSkip); result.Action != postFileSkipped {
+		t.Errorf("expected skipped, got %v", result)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const pruneActionList = "list"
+const pruneActionDelete = "delete"
+const pruneActionArchive = "archive"
+
+const prunedFileListed = "listed"
+const prunedFileDeleted = "deleted"
+const prunedFileArchived = "archived"
+const prunedFileMissing = "missing"
+const prunedFileKeptEdited = "kept (edited)"
+
+// PrunedFile a generated post file that no longer belongs to any filtered repo
+type PrunedFile struct {
+	PostFileName string `json:"postFileName"`
+	Repo         string `json:"repo"`
+	Edited       bool   `json:"edited"`
+	Action       string `json:"action"`
+}
+
+// getOrphanedPostFiles files in the manifest, posts and their social cards, that none of repoPosts would write
+func getOrphanedPostFiles(state *GeneratedPostsState, repoPosts []RepoPost, destinationDirectory string) []string {
+	cardDirectory, err := getSocialCardDirectory(destinationDirectory)
+	if err != nil {
+		// the cards can't be written either, so they are all orphaned
+		log.Warnf("getSocialCardDirectory(%s) failed: %v\n", destinationDirectory, err)
+	}
+	current := make(map[string]bool)
+	for i, repoPost := range repoPosts {
+		current[filepath.ToSlash(repoPost.PostFileName)] = true
+		if cardDirectory != "" {
+			current[getSocialCardManifestName(&repoPosts[i], cardDirectory, destinationDirectory)] = true
+		}
+	}
+
+	orphans := make([]string, 0)
+	for name := range state.Files {
+		if !current[name] {
+			orphans = append(orphans, name)
+		}
+	}
+	sort.Strings(orphans)
+	return orphans
+}
+
+func moveFile(src string, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	// rename fails across file systems
+	if err := copyFile(src, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// getArchivePath where an orphaned file is archived. a card outside the destination directory,
+// e.g. ../static/images/cards/lambda-playground.png, goes to static/images/cards/lambda-playground.png in the archive
+func getArchivePath(archiveDirectory string, name string) string {
+	parts := strings.Split(name, "/")
+	for len(parts) > 1 && (parts[0] == ".." || parts[0] == ".") {
+		parts = parts[1:]
+	}
+	return filepath.Join(archiveDirectory, filepath.FromSlash(strings.Join(parts, "/")))
+}
+
+// prunePostFiles lists, deletes or archives the generated post files in destinationDirectory that no longer belong to a repo post.
+// only files recorded in the manifest are touched and hand edited files are only deleted when -on-edited is overwrite
+func prunePostFiles(repoPosts []RepoPost, destinationDirectory string, action string, archiveDirectory string) ([]PrunedFile, error) {
+	if action != pruneActionList && action != pruneActionDelete && action != pruneActionArchive {
+		return nil, fmt.Errorf("unknown prune action %q", action)
+	}
+	if action == pruneActionArchive && archiveDirectory == "" {
+		return nil, fmt.Errorf("-archive-directory is required to archive pruned files")
+	}
+	if action != pruneActionList && len(repoPosts) == 0 {
+		return nil, fmt.Errorf("refusing to %s every generated post. no repos matched the filters", action)
+	}
+
+	state, err := loadGeneratedPostsState(destinationDirectory)
+	if err != nil {
+		log.Printf("loadGeneratedPostsState(%s) failed\n", destinationDirectory)
+		return nil, err
+	}
+
+	pruned := make([]PrunedFile, 0)
+	for _, name := range getOrphanedPostFiles(state, repoPosts, destinationDirectory) {
+		generated := state.Files[name]
+		prunedFile := PrunedFile{PostFileName: name, Repo: generated.Repo, Action: prunedFileListed}
+		path := filepath.Join(destinationDirectory, filepath.FromSlash(name))
+
+		b, err := ioutil.ReadFile(path)
+		switch {
+		case os.IsNotExist(err):
+			prunedFile.Action = prunedFileMissing
+		case err != nil:
+			return pruned, err
+		default:
+			prunedFile.Edited = getContentHash(string(b)) != generated.SHA256
+		}
+
+		if prunedFile.Action == prunedFileListed && action == pruneActionDelete {
+			if prunedFile.Edited && onEdited != onEditedOverwrite {
+				prunedFile.Action = prunedFileKeptEdited
+			} else if err := os.Remove(path); err != nil {
+				log.Printf("os.Remove(%s) failed\n", path)
+				return pruned, err
+			} else {
+				prunedFile.Action = prunedFileDeleted
+			}
+		}
+		if prunedFile.Action == prunedFileListed && action == pruneActionArchive {
+			archivePath := getArchivePath(archiveDirectory, name)
+			if err := moveFile(path, archivePath); err != nil {
+				log.Printf("moveFile(%s, %s) failed\n", path, archivePath)
+				return pruned, err
+			}
+			prunedFile.Action = prunedFileArchived
+		}
+
+		if action != pruneActionList && prunedFile.Action != prunedFileKeptEdited {
+			delete(state.Files, name)
+		}
+		pruned = append(pruned, prunedFile)
+	}
+
+	if action != pruneActionList {
+		if err := saveGeneratedPostsState(destinationDirectory, state); err != nil {
+			log.Printf("saveGeneratedPostsState(%s) failed\n", destinationDirectory)
+			return pruned, err
+		}
+	}
+	return pruned, nil
+}
+
+func writePruneReport(w io.Writer, pruned []PrunedFile, format string) error {
+	if format == "json" {
+		b, err := json.MarshalIndent(pruned, "", "  ")
+		if err != nil {
+			log.Printf("json.MarshalIndent failed\n")
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+
+	for _, prunedFile := range pruned {
+		edited := ""
+		if prunedFile.Edited {
+			edited = ", edited"
+		}
+		fmt.Fprintf(w, "%s (%s%s): %s\n", prunedFile.PostFileName, prunedFile.Repo, edited, prunedFile.Action)
+	}
+	fmt.Fprintf(w, "%d orphaned post files\n", len(pruned))
+	return nil
+}
+
+func prunePostFilesForUser(username string, destinationDirectory string, action string, archiveDirectory string, w io.Writer, format string) error {
+	repoPosts, err := getRepoPosts(username)
+	if err != nil {
+		log.Printf("getRepoPosts(%s) failed\n", username)
+		return err
+	}
+
+	pruned, err := prunePostFiles(repoPosts, destinationDirectory, action, archiveDirectory)
+	if err != nil {
+		log.Printf("prunePostFiles(%s) failed\n", destinationDirectory)
+		return err
+	}
+	return writePruneReport(w, pruned, format)
+}
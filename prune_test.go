@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrunePostFiles(t *testing.T) {
+	destinationDirectory, err := ioutil.TempDir("", "posts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(destinationDirectory)
+	archiveDirectory := filepath.Join(destinationDirectory, "..", filepath.Base(destinationDirectory)+"-archive")
+	defer os.RemoveAll(archiveDirectory)
+
+	state := &GeneratedPostsState{Files: make(map[string]GeneratedFile)}
+	repoPosts := make([]RepoPost, 0)
+	for _, name := range []string{"kept-playground", "renamed-playground", "edited-playground", "deleted-playground"} {
+		repoPost := RepoPost{Repo: newTestRepo(name), PostFileName: "generated-" + name + ".md", PostFileContents: name + "\n"}
+		if _, err := writeGeneratedPostFile(repoPost, destinationDirectory, state, onEditedSkip); err != nil {
+			t.Fatal(err)
+		}
+		repoPosts = append(repoPosts, repoPost)
+	}
+	if err := saveGeneratedPostsState(destinationDirectory, state); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(destinationDirectory, "generated-edited-playground.md"), []byte("notes\n"), 0644)
+	os.Remove(filepath.Join(destinationDirectory, "generated-deleted-playground.md"))
+	ioutil.WriteFile(filepath.Join(destinationDirectory, "about.md"), []byte("hand written\n"), 0644)
+
+	current := repoPosts[:1]
+	pruned, err := prunePostFiles(current, destinationDirectory, pruneActionList, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned) != 3 || pruned[0].PostFileName != "generated-deleted-playground.md" || pruned[0].Action != prunedFileMissing || !pruned[1].Edited {
+		t.Errorf("unexpected list %+v", pruned)
+	}
+
+	pruned, err = prunePostFiles(current, destinationDirectory, pruneActionDelete, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned[1].Action != prunedFileKeptEdited || pruned[2].Action != prunedFileDeleted {
+		t.Errorf("unexpected delete %+v", pruned)
+	}
+	if fileExists(filepath.Join(destinationDirectory, "generated-renamed-playground.md")) {
+		t.Error("expected renamed post to be deleted")
+	}
+	if !fileExists(filepath.Join(destinationDirectory, "about.md")) || !fileExists(filepath.Join(destinationDirectory, "generated-kept-playground.md")) {
+		t.Error("expected files outside the manifest and current posts to be kept")
+	}
+
+	pruned, err = prunePostFiles(current, destinationDirectory, pruneActionArchive, archiveDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned) != 1 || pruned[0].Action != prunedFileArchived || !fileExists(filepath.Join(archiveDirectory, "generated-edited-playground.md")) {
+		t.Errorf("unexpected archive %+v", pruned)
+	}
+
+	var buf bytes.Buffer
+	if err := writePruneReport(&buf, pruned, "text"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "generated-edited-playground.md (pfeilbr/edited-playground, edited): archived") {
+		t.Errorf("unexpected report %s", buf.String())
+	}
+
+	if _, err := prunePostFiles(nil, destinationDirectory, pruneActionDelete, ""); err == nil {
+		t.Error("expected an error deleting with no repo posts")
+	}
+}
+
+func TestPruneSocialCards(t *testing.T) {
+	directory, err := ioutil.TempDir("", "site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	destinationDirectory := filepath.Join(directory, "content", "post")
+	staticDirectory := filepath.Join(directory, "static", "cards")
+	withTestConfig(t, "", "outputs.socialCards.mode=static", "outputs.socialCards.staticDirectory="+staticDirectory)
+
+	state := &GeneratedPostsState{Files: make(map[string]GeneratedFile)}
+	repoPosts := make([]RepoPost, 0)
+	for _, name := range []string{"kept-playground", "deleted-playground"} {
+		repoPost := RepoPost{Repo: newTestRepo(name), Title: name, Slug: name, PostFileName: "generated-" + name + ".md", PostFileContents: name + "\n"}
+		if _, err := writeGeneratedPostFile(repoPost, destinationDirectory, state, onEditedSkip); err != nil {
+			t.Fatal(err)
+		}
+		repoPosts = append(repoPosts, repoPost)
+	}
+	if err := writeSocialCards(repoPosts, destinationDirectory, state); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveGeneratedPostsState(destinationDirectory, state); err != nil {
+		t.Fatal(err)
+	}
+
+	pruned, err := prunePostFiles(repoPosts[:1], destinationDirectory, pruneActionDelete, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pruned) != 2 || pruned[0].PostFileName != "../../static/cards/deleted-playground.png" || pruned[0].Action != prunedFileDeleted {
+		t.Errorf("expected the orphaned card to be deleted with its post, got %+v", pruned)
+	}
+	if fileExists(filepath.Join(staticDirectory, "deleted-playground.png")) || !fileExists(filepath.Join(staticDirectory, "kept-playground.png")) {
+		t.Error("expected only the card of the deleted post to be removed")
+	}
+
+	if path := getArchivePath("archive", "../../static/cards/deleted-playground.png"); path != filepath.Join("archive", "static", "cards", "deleted-playground.png") {
+		t.Errorf("expected the card to stay inside the archive, got %s", path)
+	}
+}
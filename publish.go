@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultPublishContentPath = "content/post"
+const defaultPublishRemote = "origin"
+
+// PublishOptions where generated posts are published to
+type PublishOptions struct {
+	RepoURL       string
+	Branch        string
+	ContentPath   string
+	WorkDirectory string
+	Remote        string
+	Push          bool
+	AuthorName    string
+	AuthorEmail   string
+	CommitSubject string
+}
+
+// PublishResult the posts a publish added, updated and removed
+type PublishResult struct {
+	Added     []string
+	Updated   []string
+	Removed   []string
+	Committed bool
+	Pushed    bool
+}
+
+func getPublishOptions() (PublishOptions, error) {
+	config := getConfig().Outputs.Publish
+	options := PublishOptions{
+		RepoURL:       config.RepoURL,
+		Branch:        config.Branch,
+		ContentPath:   config.ContentPath,
+		WorkDirectory: config.WorkDirectory,
+		Remote:        defaultPublishRemote,
+		Push:          config.Push,
+		AuthorName:    config.AuthorName,
+		AuthorEmail:   config.AuthorEmail,
+		CommitSubject: config.CommitSubject,
+	}
+	if options.RepoURL == "" {
+		return options, fmt.Errorf("outputs.publish.repoURL (PUBLISH_REPO_URL) is required to publish")
+	}
+	if options.ContentPath == "" {
+		options.ContentPath = defaultPublishContentPath
+	}
+	if options.WorkDirectory == "" {
+		options.WorkDirectory = filepath.Join(tempDirectoryName, "publish")
+		// profiles usually publish to different repos
+		if profile != "" {
+			options.WorkDirectory += "-" + profile
+		}
+	}
+	if options.CommitSubject == "" {
+		options.CommitSubject = "Update generated posts"
+	}
+	return options, nil
+}
+
+// runGit runs git in dir. the error includes the output of git
+func runGit(dir string, args ...string) (string, error) {
+	if debug {
+		log.Printf("git %s\n", strings.Join(args, " "))
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// openPublishRepo clones the repo into the work directory or updates an earlier clone to the tip of the branch
+func openPublishRepo(options *PublishOptions) error {
+	if _, err := os.Stat(filepath.Join(options.WorkDirectory, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(options.WorkDirectory), os.ModePerm); err != nil {
+			return err
+		}
+		if _, err := runGit(".", "clone", "--origin", options.Remote, options.RepoURL, options.WorkDirectory); err != nil {
+			return err
+		}
+	} else if _, err := runGit(options.WorkDirectory, "fetch", "--prune", options.Remote); err != nil {
+		return err
+	}
+
+	if options.Branch == "" {
+		// the default branch of the clone, which exists even when the repo is empty
+		branch, err := runGit(options.WorkDirectory, "symbolic-ref", "--short", "HEAD")
+		if err != nil {
+			return err
+		}
+		options.Branch = strings.TrimSpace(branch)
+	}
+
+	remoteBranch := options.Remote + "/" + options.Branch
+	if _, err := runGit(options.WorkDirectory, "rev-parse", "--verify", "--quiet", remoteBranch); err == nil {
+		_, err = runGit(options.WorkDirectory, "checkout", "-B", options.Branch, remoteBranch)
+		return err
+	}
+	_, err := runGit(options.WorkDirectory, "checkout", "-B", options.Branch)
+	return err
+}
+
+func getPublishCommitMessage(subject string, result PublishResult) string {
+	var sb strings.Builder
+	sb.WriteString(subject + "\n")
+	for _, section := range []struct {
+		name  string
+		posts []string
+	}{{"Added", result.Added}, {"Updated", result.Updated}, {"Removed", result.Removed}} {
+		if len(section.posts) == 0 {
+			continue
+		}
+		sb.WriteString("\n" + section.name + ":\n")
+		for _, post := range section.posts {
+			sb.WriteString("- " + post + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// getPublishPaths the literal pathspecs, relative to the work directory, of every file in the manifest, the files pruned from it
+// and the generated posts state. sidecar files and anything else in the content path are left out.
+// files outside the work directory, like social cards in a static directory elsewhere, can't be published
+func getPublishPaths(contentDirectory string, workDirectory string, pruned []PrunedFile) ([]string, error) {
+	state, err := loadGeneratedPostsState(contentDirectory)
+	if err != nil {
+		log.Printf("loadGeneratedPostsState(%s) failed\n", contentDirectory)
+		return nil, err
+	}
+	absoluteWorkDirectory, err := filepath.Abs(workDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{getGeneratedPostsStatePath(contentDirectory)}
+	for name := range state.Files {
+		files = append(files, filepath.Join(contentDirectory, filepath.FromSlash(name)))
+	}
+	for _, prunedFile := range pruned {
+		files = append(files, filepath.Join(contentDirectory, filepath.FromSlash(prunedFile.PostFileName)))
+	}
+
+	paths := make([]string, 0, len(files))
+	seen := make(map[string]bool)
+	for _, file := range files {
+		absoluteFile, err := filepath.Abs(file)
+		if err != nil {
+			return nil, err
+		}
+		path, err := filepath.Rel(absoluteWorkDirectory, absoluteFile)
+		if err != nil || path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+			log.Warnf("%s is outside of the publish work directory %s and isn't published\n", file, workDirectory)
+			continue
+		}
+		path = ":(literal)" + filepath.ToSlash(path)
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// stagePublishPaths stages the paths that exist and the removal of the ones that don't
+func stagePublishPaths(workDirectory string, paths []string) error {
+	added := make([]string, 0)
+	removed := make([]string, 0)
+	for _, path := range paths {
+		if _, err := os.Stat(filepath.Join(workDirectory, filepath.FromSlash(strings.TrimPrefix(path, ":(literal)")))); err == nil {
+			added = append(added, path)
+		} else {
+			removed = append(removed, path)
+		}
+	}
+	if len(added) > 0 {
+		if _, err := runGit(workDirectory, append([]string{"add", "--"}, added...)...); err != nil {
+			return err
+		}
+	}
+	if len(removed) > 0 {
+		if _, err := runGit(workDirectory, append([]string{"rm", "--cached", "--quiet", "--ignore-unmatch", "--"}, removed...)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishPosts writes the posts into the content path of the publish repo, deletes posts of repos that are gone,
+// commits the changes to the generated files with a message listing them and pushes when configured
+func publishPosts(repoPosts []RepoPost, options PublishOptions) (PublishResult, error) {
+	result := PublishResult{Added: make([]string, 0), Updated: make([]string, 0), Removed: make([]string, 0)}
+
+	if err := openPublishRepo(&options); err != nil {
+		log.Printf("openPublishRepo(%s) failed\n", options.RepoURL)
+		return result, err
+	}
+
+	contentDirectory := filepath.Join(options.WorkDirectory, filepath.FromSlash(options.ContentPath))
+	results, err := writePostFiles(repoPosts, contentDirectory)
+	if err != nil {
+		log.Printf("writePostFiles(%s) failed\n", contentDirectory)
+		return result, err
+	}
+
+	titles := make(map[string]string)
+	for _, repoPost := range repoPosts {
+		titles[repoPost.PostFileName] = repoPost.Title
+	}
+	for _, postFileResult := range results {
+		post := fmt.Sprintf("%s (%s)", titles[postFileResult.PostFileName], filepath.ToSlash(postFileResult.PostFileName))
+		switch postFileResult.Action {
+		case postFileCreated:
+			result.Added = append(result.Added, post)
+		case postFileUpdated, postFileMerged:
+			result.Updated = append(result.Updated, post)
+		}
+	}
+
+	pruned, err := prunePostFiles(repoPosts, contentDirectory, pruneActionDelete, "")
+	if err != nil {
+		log.Printf("prunePostFiles(%s) failed\n", contentDirectory)
+		return result, err
+	}
+	for _, prunedFile := range pruned {
+		if prunedFile.Action == prunedFileDeleted {
+			result.Removed = append(result.Removed, fmt.Sprintf("%s (%s)", prunedFile.Repo, prunedFile.PostFileName))
+		}
+	}
+
+	paths, err := getPublishPaths(contentDirectory, options.WorkDirectory, pruned)
+	if err != nil {
+		log.Printf("getPublishPaths(%s) failed\n", contentDirectory)
+		return result, err
+	}
+	if err := stagePublishPaths(options.WorkDirectory, paths); err != nil {
+		return result, err
+	}
+	status, err := runGit(options.WorkDirectory, append([]string{"status", "--porcelain", "--"}, paths...)...)
+	if err != nil {
+		return result, err
+	}
+	if strings.TrimSpace(status) == "" {
+		log.Printf("nothing to publish\n")
+		return result, nil
+	}
+
+	commit := make([]string, 0)
+	if options.AuthorName != "" {
+		commit = append(commit, "-c", "user.name="+options.AuthorName)
+	}
+	if options.AuthorEmail != "" {
+		commit = append(commit, "-c", "user.email="+options.AuthorEmail)
+	}
+	commit = append(commit, "commit", "--quiet", "-m", getPublishCommitMessage(options.CommitSubject, result))
+	if _, err := runGit(options.WorkDirectory, commit...); err != nil {
+		return result, err
+	}
+	result.Committed = true
+
+	if options.Push {
+		if _, err := runGit(options.WorkDirectory, "push", options.Remote, options.Branch); err != nil {
+			return result, err
+		}
+		result.Pushed = true
+	}
+	return result, nil
+}
+
+func publishPostsForUser(username string) error {
+	options, err := getPublishOptions()
+	if err != nil {
+		log.Printf("getPublishOptions() failed\n")
+		return err
+	}
+	repoPosts, err := getRepoPosts(username)
+	if err != nil {
+		log.Printf("getRepoPosts(%s) failed\n", username)
+		return err
+	}
+
+	result, err := publishPosts(repoPosts, options)
+	if err != nil {
+		return err
+	}
+	if err := recordPublishedSlugs(repoPosts); err != nil {
+		return err
+	}
+	log.Printf("publish: %d added, %d updated, %d removed, committed: %t, pushed: %t\n", len(result.Added), len(result.Updated), len(result.Removed), result.Committed, result.Pushed)
+	return nil
+}
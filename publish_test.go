@@ -0,0 +1,81 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPublishPosts(t *testing.T) {
+	directory := t.TempDir()
+	cardDirectory := filepath.Join(directory, "work", "static", "images", "cards")
+	withTestConfig(t, "outputs:\n  socialCards:\n    mode: static\n    staticDirectory: "+cardDirectory+"\n")
+
+	bareRepo := filepath.Join(directory, "site.git")
+	if _, err := runGit(directory, "init", "--quiet", "--bare", "--initial-branch=main", bareRepo); err != nil {
+		t.Fatal(err)
+	}
+	options := PublishOptions{
+		RepoURL:       bareRepo,
+		ContentPath:   "content/post",
+		WorkDirectory: filepath.Join(directory, "work"),
+		Remote:        defaultPublishRemote,
+		Push:          true,
+		AuthorName:    "Publisher",
+		AuthorEmail:   "publisher@example.com",
+		CommitSubject: "Update generated posts",
+	}
+
+	repoPost := func(name string, contents string) RepoPost {
+		return RepoPost{Repo: newTestRepo(name), Title: name, Slug: name, PostFileName: "generated-" + name + ".md", PostFileContents: contents}
+	}
+
+	result, err := publishPosts([]RepoPost{repoPost("lambda-playground", "lambda\n"), repoPost("s3-playground", "s3\n")}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Committed || !result.Pushed || len(result.Added) != 2 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	// files the run didn't write aren't published
+	contentDirectory := filepath.Join(options.WorkDirectory, "content", "post")
+	ioutil.WriteFile(filepath.Join(contentDirectory, "generated-lambda-playground.md"+sidecarFileExtension), []byte("merge leftovers\n"), 0644)
+	ioutil.WriteFile(filepath.Join(contentDirectory, "notes.md"), []byte("draft\n"), 0644)
+
+	result, err = publishPosts([]RepoPost{repoPost("lambda-playground", "lambda v2\n")}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the post and its card
+	if len(result.Updated) != 1 || len(result.Removed) != 2 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	message, err := runGit(directory, "--git-dir", bareRepo, "log", "-1", "--format=%an%n%B", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Publisher", "Updated:\n- lambda-playground (generated-lambda-playground.md)", "Removed:\n- pfeilbr/s3-playground (../../static/images/cards/s3-playground.png)\n- pfeilbr/s3-playground (generated-s3-playground.md)"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("expected %q in commit message %s", want, message)
+		}
+	}
+
+	files, err := runGit(directory, "--git-dir", bareRepo, "ls-tree", "-r", "--name-only", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files != "content/post/.generated-posts.json\ncontent/post/generated-lambda-playground.md\nstatic/images/cards/lambda-playground.png\n" {
+		t.Errorf("unexpected files %q", files)
+	}
+
+	result, err = publishPosts([]RepoPost{repoPost("lambda-playground", "lambda v2\n")}, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Committed {
+		t.Errorf("expected nothing to commit, got %+v", result)
+	}
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+	"golang.org/x/net/html"
+)
+
+const sanitizeActionAllow = "allow"
+const sanitizeActionStrip = "strip"
+const sanitizeActionEscape = "escape"
+
+// HTMLSanitizerPolicy what happens to each raw HTML tag and attribute in a README.
+// Tags map a tag name to allow, strip or escape. Attributes lists the attributes kept on allowed tags, "*" applies to every tag.
+type HTMLSanitizerPolicy struct {
+	DefaultAction string              `json:"defaultAction" yaml:"defaultAction"`
+	Tags          map[string]string   `json:"tags" yaml:"tags"`
+	Attributes    map[string][]string `json:"attributes" yaml:"attributes"`
+}
+
+// HTMLSanitizerRemoval a tag or attribute the sanitizer did not allow through
+type HTMLSanitizerRemoval struct {
+	Tag       string
+	Attribute string
+	Action    string
+}
+
+func (removal HTMLSanitizerRemoval) String() string {
+	if removal.Attribute != "" {
+		return fmt.Sprintf("%s attribute %s on <%s>", removal.Action, removal.Attribute, removal.Tag)
+	}
+	return fmt.Sprintf("%s <%s>", removal.Action, removal.Tag)
+}
+
+var defaultHTMLSanitizerPolicy = HTMLSanitizerPolicy{
+	DefaultAction: sanitizeActionEscape,
+	Tags: map[string]string{
+		"a": sanitizeActionAllow, "abbr": sanitizeActionAllow, "b": sanitizeActionAllow, "blockquote": sanitizeActionAllow,
+		"br": sanitizeActionAllow, "caption": sanitizeActionAllow, "center": sanitizeActionAllow, "code": sanitizeActionAllow,
+		"dd": sanitizeActionAllow, "del": sanitizeActionAllow, "details": sanitizeActionAllow, "div": sanitizeActionAllow,
+		"dl": sanitizeActionAllow, "dt": sanitizeActionAllow, "em": sanitizeActionAllow, "figcaption": sanitizeActionAllow,
+		"figure": sanitizeActionAllow, "h1": sanitizeActionAllow, "h2": sanitizeActionAllow, "h3": sanitizeActionAllow,
+		"h4": sanitizeActionAllow, "h5": sanitizeActionAllow, "h6": sanitizeActionAllow, "hr": sanitizeActionAllow,
+		"i": sanitizeActionAllow, "img": sanitizeActionAllow, "ins": sanitizeActionAllow, "kbd": sanitizeActionAllow,
+		"li": sanitizeActionAllow, "ol": sanitizeActionAllow, "p": sanitizeActionAllow, "picture": sanitizeActionAllow,
+		"pre": sanitizeActionAllow, "s": sanitizeActionAllow, "source": sanitizeActionAllow, "span": sanitizeActionAllow,
+		"strong": sanitizeActionAllow, "sub": sanitizeActionAllow, "summary": sanitizeActionAllow, "sup": sanitizeActionAllow,
+		"table": sanitizeActionAllow, "tbody": sanitizeActionAllow, "td": sanitizeActionAllow, "tfoot": sanitizeActionAllow,
+		"th": sanitizeActionAllow, "thead": sanitizeActionAllow, "tr": sanitizeActionAllow, "u": sanitizeActionAllow,
+		"ul": sanitizeActionAllow, "embed": sanitizeActionStrip, "form": sanitizeActionStrip, "iframe": sanitizeActionStrip, "link": sanitizeActionStrip,
+		"meta": sanitizeActionStrip, "object": sanitizeActionStrip, "script": sanitizeActionStrip, "style": sanitizeActionStrip,
+	},
+	Attributes: map[string][]string{
+		"*":       {"align", "title"},
+		"a":       {"href", "name"},
+		"img":     {"src", "alt", "width", "height"},
+		"source":  {"srcset", "media", "type"},
+		"details": {"open"},
+		"td":      {"colspan", "rowspan"},
+		"th":      {"colspan", "rowspan"},
+		"ol":      {"start"},
+	},
+}
+
+// tags whose contents are removed along with the tag when stripped
+var sanitizerDropContentTags = map[string]bool{
+	"embed":    true,
+	"iframe":   true,
+	"noscript": true,
+	"object":   true,
+	"script":   true,
+	"style":    true,
+	"template": true,
+	"textarea": true,
+}
+
+var sanitizerURLAttributes = map[string]bool{
+	"href":   true,
+	"src":    true,
+	"srcset": true,
+}
+
+// schemes a url attribute may use. relative urls have no scheme
+var sanitizerURLSchemes = map[string]bool{
+	"":       true,
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+var unterminatedTagRegexp = regexp.MustCompile(`^</?([a-zA-Z][a-zA-Z0-9-]*)`)
+var autolinkRegexp = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.\-]{1,31}:[^\s<>]*|[^\s<>@]+@[^\s<>]+)>$`)
+
+func getHTMLSanitizerPolicy() (HTMLSanitizerPolicy, error) {
+	policy := HTMLSanitizerPolicy{
+		DefaultAction: defaultHTMLSanitizerPolicy.DefaultAction,
+		Tags:          make(map[string]string),
+		Attributes:    make(map[string][]string),
+	}
+	for tag, action := range defaultHTMLSanitizerPolicy.Tags {
+		policy.Tags[tag] = action
+	}
+	for tag, attributes := range defaultHTMLSanitizerPolicy.Attributes {
+		policy.Attributes[tag] = attributes
+	}
+
+	overrides := getConfig().Body.SanitizerPolicy
+	if overrides.DefaultAction != "" {
+		policy.DefaultAction = overrides.DefaultAction
+	}
+	for tag, action := range overrides.Tags {
+		policy.Tags[strings.ToLower(tag)] = action
+	}
+	for tag, attributes := range overrides.Attributes {
+		policy.Attributes[strings.ToLower(tag)] = attributes
+	}
+	return policy, nil
+}
+
+// isSafeURL whether a url can be kept in an href or src. browsers drop tabs, newlines and other control characters
+// from urls, so java&#9;script: is javascript:
+func isSafeURL(value string) bool {
+	value = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, value))
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	return sanitizerURLSchemes[strings.ToLower(parsed.Scheme)]
+}
+
+// isSafeURLAttribute checks every candidate url of a srcset, or the one url of other attributes
+func isSafeURLAttribute(attribute string, value string) bool {
+	if attribute != "srcset" {
+		return isSafeURL(value)
+	}
+	for _, candidate := range strings.Split(value, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) > 0 && !isSafeURL(fields[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (policy HTMLSanitizerPolicy) actionFor(tag string) string {
+	if action, ok := policy.Tags[tag]; ok {
+		return action
+	}
+	return policy.DefaultAction
+}
+
+func (policy HTMLSanitizerPolicy) allowsAttribute(tag string, attribute string) bool {
+	for _, allowed := range append(policy.Attributes["*"], policy.Attributes[tag]...) {
+		if strings.EqualFold(allowed, attribute) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeTag removes attributes that are not allowed from an allowed tag
+func (policy HTMLSanitizerPolicy) sanitizeTag(token html.Token, raw string) (string, []HTMLSanitizerRemoval) {
+	removals := make([]HTMLSanitizerRemoval, 0)
+	attributes := make([]html.Attribute, 0, len(token.Attr))
+	for _, attribute := range token.Attr {
+		if !policy.allowsAttribute(token.Data, attribute.Key) || (sanitizerURLAttributes[attribute.Key] && !isSafeURLAttribute(attribute.Key, attribute.Val)) {
+			removals = append(removals, HTMLSanitizerRemoval{Tag: token.Data, Attribute: attribute.Key, Action: sanitizeActionStrip})
+			continue
+		}
+		attributes = append(attributes, attribute)
+	}
+
+	if len(removals) == 0 {
+		return raw, removals
+	}
+	token.Attr = attributes
+	return token.String(), removals
+}
+
+// htmlSanitizer applies a policy to HTML that can arrive in pieces, like the inline HTML of a paragraph.
+// a stripped <script> in one piece drops everything up to its </script> in a later one
+type htmlSanitizer struct {
+	policy           HTMLSanitizerPolicy
+	dropContentTag   string
+	dropContentDepth int
+}
+
+// sanitizeHTMLFragment applies the policy to a complete run of HTML
+func (policy HTMLSanitizerPolicy) sanitizeHTMLFragment(text string) (string, []HTMLSanitizerRemoval) {
+	return (&htmlSanitizer{policy: policy}).sanitize(text)
+}
+
+func (sanitizer *htmlSanitizer) dropping() bool {
+	return sanitizer.dropContentDepth > 0
+}
+
+func (sanitizer *htmlSanitizer) sanitize(text string) (string, []HTMLSanitizerRemoval) {
+	var result strings.Builder
+	removals := make([]HTMLSanitizerRemoval, 0)
+	policy := sanitizer.policy
+
+	z := html.NewTokenizer(strings.NewReader(text))
+	for {
+		tokenType := z.Next()
+		if tokenType == html.ErrorToken {
+			// a tag cut off by the end of the input, e.g. <img src=x onerror=alert(1)
+			if raw := string(z.Raw()); raw != "" {
+				tag := "unterminated tag"
+				if match := unterminatedTagRegexp.FindStringSubmatch(raw); match != nil {
+					tag = strings.ToLower(match[1])
+				}
+				removals = append(removals, HTMLSanitizerRemoval{Tag: tag, Action: sanitizeActionEscape})
+				result.WriteString(html.EscapeString(raw))
+			}
+			break
+		}
+		raw := string(z.Raw())
+		token := z.Token()
+
+		if sanitizer.dropContentDepth > 0 {
+			if token.Data == sanitizer.dropContentTag && tokenType == html.StartTagToken {
+				sanitizer.dropContentDepth++
+			} else if token.Data == sanitizer.dropContentTag && tokenType == html.EndTagToken {
+				sanitizer.dropContentDepth--
+			}
+			continue
+		}
+
+		switch tokenType {
+		case html.TextToken, html.CommentToken:
+			result.WriteString(raw)
+		case html.DoctypeToken:
+			removals = append(removals, HTMLSanitizerRemoval{Tag: "!doctype", Action: sanitizeActionStrip})
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			if autolinkRegexp.MatchString(raw) {
+				result.WriteString(raw)
+				continue
+			}
+
+			action := policy.actionFor(token.Data)
+			switch action {
+			case sanitizeActionAllow:
+				if tokenType == html.EndTagToken {
+					result.WriteString(raw)
+					continue
+				}
+				sanitized, tagRemovals := policy.sanitizeTag(token, raw)
+				result.WriteString(sanitized)
+				removals = append(removals, tagRemovals...)
+			case sanitizeActionStrip:
+				if tokenType != html.EndTagToken {
+					removals = append(removals, HTMLSanitizerRemoval{Tag: token.Data, Action: action})
+				}
+				if tokenType == html.StartTagToken && sanitizerDropContentTags[token.Data] {
+					sanitizer.dropContentTag = token.Data
+					sanitizer.dropContentDepth = 1
+				}
+			default:
+				if tokenType != html.EndTagToken {
+					removals = append(removals, HTMLSanitizerRemoval{Tag: token.Data, Action: sanitizeActionEscape})
+				}
+				result.WriteString(html.EscapeString(raw))
+			}
+		}
+	}
+	return result.String(), removals
+}
+
+// markdownEdit replaces markdown[start:stop] with text
+type markdownEdit struct {
+	start int
+	stop  int
+	text  string
+}
+
+// getBlockAncestor the paragraph, heading or other block an inline node is part of
+func getBlockAncestor(node ast.Node) ast.Node {
+	for node.Parent() != nil && node.Type() != ast.TypeBlock {
+		node = node.Parent()
+	}
+	return node
+}
+
+// sanitizeMarkdownSegments sanitizes the lines of one piece of raw HTML. nil when nothing changed
+func sanitizeMarkdownSegments(markdown string, segments []text.Segment, sanitizer *htmlSanitizer) (*markdownEdit, []HTMLSanitizerRemoval) {
+	var raw strings.Builder
+	prefixes := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		if i > 0 {
+			prefixes = append(prefixes, markdown[segments[i-1].Stop:segment.Start])
+		}
+		raw.WriteString(markdown[segment.Start:segment.Stop])
+	}
+	sanitized, removals := sanitizer.sanitize(raw.String())
+	if sanitized == raw.String() {
+		return nil, removals
+	}
+
+	// the lines after the first keep the list item or block quote prefix they had
+	var result strings.Builder
+	for i, line := range strings.SplitAfter(sanitized, "\n") {
+		if i > 0 && line != "" && len(prefixes) > 0 {
+			if i > len(prefixes) {
+				i = len(prefixes)
+			}
+			result.WriteString(prefixes[i-1])
+		}
+		result.WriteString(line)
+	}
+	return &markdownEdit{start: segments[0].Start, stop: segments[len(segments)-1].Stop, text: result.String()}, removals
+}
+
+// sanitizeMarkdownHTML applies the policy to the HTML blocks and inline HTML of markdown as a CommonMark parser finds them,
+// so a fence inside an HTML block is HTML too. code blocks and code spans are left alone
+func sanitizeMarkdownHTML(markdown string, policy HTMLSanitizerPolicy) (string, []HTMLSanitizerRemoval) {
+	source := []byte(markdown)
+	edits := make([]markdownEdit, 0)
+	removals := make([]HTMLSanitizerRemoval, 0)
+	inlineSanitizers := make(map[ast.Node]*htmlSanitizer)
+	inlineStops := make(map[ast.Node]int)
+
+	document := goldmark.New(goldmark.WithExtensions(extension.GFM)).Parser().Parse(text.NewReader(source))
+	ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var segments []text.Segment
+		sanitizer := &htmlSanitizer{policy: policy}
+		switch node := node.(type) {
+		case *ast.HTMLBlock:
+			segments = append(segments, node.Lines().Sliced(0, node.Lines().Len())...)
+			if node.HasClosure() {
+				segments = append(segments, node.ClosureLine)
+			}
+		case *ast.RawHTML:
+			segments = node.Segments.Sliced(0, node.Segments.Len())
+			block := getBlockAncestor(node)
+			if inlineSanitizer, ok := inlineSanitizers[block]; ok {
+				sanitizer = inlineSanitizer
+			}
+			inlineSanitizers[block] = sanitizer
+			// the markdown between a stripped <script> and its </script> is script contents
+			if len(segments) > 0 && sanitizer.dropping() {
+				edits = append(edits, markdownEdit{start: inlineStops[block], stop: segments[0].Start})
+			}
+			if len(segments) > 0 {
+				inlineStops[block] = segments[len(segments)-1].Stop
+			}
+		}
+		if len(segments) == 0 {
+			return ast.WalkContinue, nil
+		}
+		edit, segmentRemovals := sanitizeMarkdownSegments(markdown, segments, sanitizer)
+		removals = append(removals, segmentRemovals...)
+		if edit != nil {
+			edits = append(edits, *edit)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	var result strings.Builder
+	last := 0
+	for _, edit := range edits {
+		result.WriteString(markdown[last:edit.start])
+		result.WriteString(edit.text)
+		last = edit.stop
+	}
+	result.WriteString(markdown[last:])
+	return result.String(), removals
+}
+
+func sanitizePostBody(repoName string, markdown string) (string, error) {
+	policy, err := getHTMLSanitizerPolicy()
+	if err != nil {
+		log.Printf("getHTMLSanitizerPolicy() failed\n")
+		return "", err
+	}
+
+	sanitized, removals := sanitizeMarkdownHTML(markdown, policy)
+	for _, removal := range removals {
+		log.Printf("sanitizePostBody(%s) %s\n", repoName, removal)
+	}
+	return sanitized, nil
+}
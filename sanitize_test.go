@@ -0,0 +1,138 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeMarkdownHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+		removed  int
+	}{
+		{
+			name:     "script removed with contents",
+			markdown: "before <script>alert('x')</script> after",
+			want:     "before  after",
+			removed:  1,
+		},
+		{
+			name:     "iframe removed with contents",
+			markdown: "<iframe src=\"https://evil.example\"><p>fallback</p></iframe>\n",
+			want:     "\n",
+			removed:  1,
+		},
+		{
+			name:     "event handler and style attributes removed",
+			markdown: "<img src=\"x.png\" onerror=\"alert(1)\" style=\"color: red\">",
+			want:     "<img src=\"x.png\">",
+			removed:  2,
+		},
+		{
+			name:     "javascript url removed",
+			markdown: "<a href=\"javascript:alert(1)\">click</a>",
+			want:     "<a>click</a>",
+			removed:  1,
+		},
+		{
+			name:     "entity encoded tab in javascript url removed",
+			markdown: "<a href=\"java&#9;script:alert(1)\">click</a>",
+			want:     "<a>click</a>",
+			removed:  1,
+		},
+		{
+			name:     "literal whitespace in javascript url removed",
+			markdown: "<a href=\"java\tscript:alert(1)\">a</a> <img src=\" \x01java\nscript:alert(1)\">",
+			want:     "<a>a</a> <img>",
+			removed:  2,
+		},
+		{
+			name:     "data url in srcset removed",
+			markdown: "<source srcset=\"a.png 1x, data:image/svg+xml,x 2x\">",
+			want:     "<source>",
+			removed:  1,
+		},
+		{
+			name:     "unknown tag escaped",
+			markdown: "use List<String> here",
+			want:     "use List&lt;String&gt; here",
+			removed:  1,
+		},
+		{
+			name:     "allowed html untouched",
+			markdown: "<p align=\"center\">\n  <img src=\"logo.png\" width=\"100\">\n</p>\n<!-- comment -->",
+			want:     "<p align=\"center\">\n  <img src=\"logo.png\" width=\"100\">\n</p>\n<!-- comment -->",
+		},
+		{
+			name:     "code and autolinks untouched",
+			markdown: "`<script>` <https://example.com> <me@example.com>\n\n```html\n<script>x</script>\n```\n",
+			want:     "`<script>` <https://example.com> <me@example.com>\n\n```html\n<script>x</script>\n```\n",
+		},
+		{
+			name:     "fence inside an html block is html",
+			markdown: "<div>\n```\n</div>\n<script>alert(1)</script>\n",
+			want:     "<div>\n```\n</div>\n\n",
+			removed:  1,
+		},
+		{
+			name:     "html block in a list item keeps its indent",
+			markdown: "- <div>\n  ```\n  </div>\n  <img src=x onerror=alert(1)>\n",
+			want:     "- <div>\n  ```\n  </div>\n  <img src=\"x\">\n",
+			removed:  1,
+		},
+		{
+			name:     "html across lines of a block quote",
+			markdown: "> <div\n> onclick=\"alert(1)\">\n> hi\n",
+			want:     "> <div>\n> hi\n",
+			removed:  1,
+		},
+		{
+			name:     "markdown comparison untouched",
+			markdown: "if a < b && b > c",
+			want:     "if a < b && b > c",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, removals := sanitizeMarkdownHTML(test.markdown, defaultHTMLSanitizerPolicy)
+			if result != test.want {
+				t.Errorf("got %q, want %q", result, test.want)
+			}
+			if len(removals) != test.removed {
+				t.Errorf("got %d removals %v, want %d", len(removals), removals, test.removed)
+			}
+		})
+	}
+}
+
+func TestSanitizeMarkdownHTMLPolicy(t *testing.T) {
+	policy := HTMLSanitizerPolicy{
+		DefaultAction: sanitizeActionStrip,
+		Tags:          map[string]string{"script": sanitizeActionEscape, "span": sanitizeActionAllow},
+		Attributes:    map[string][]string{"span": {"style"}},
+	}
+
+	result, _ := sanitizeMarkdownHTML("<script>x</script><span style=\"color: red\">hi</span><marquee>!</marquee>", policy)
+	want := "&lt;script&gt;x&lt;/script&gt;<span style=\"color: red\">hi</span>!"
+	if result != want {
+		t.Errorf("got %q, want %q", result, want)
+	}
+
+	removal := HTMLSanitizerRemoval{Tag: "img", Attribute: "onerror", Action: sanitizeActionStrip}
+	if !strings.Contains(removal.String(), "onerror") {
+		t.Errorf("expected attribute in %q", removal.String())
+	}
+}
+
+func TestSanitizeHTMLFragmentUnterminatedTag(t *testing.T) {
+	result, removals := defaultHTMLSanitizerPolicy.sanitizeHTMLFragment("<img src=x onerror=alert(1)")
+	if result != "&lt;img src=x onerror=alert(1)" {
+		t.Errorf("got %q", result)
+	}
+	if len(removals) != 1 || removals[0].Tag != "img" || removals[0].Action != sanitizeActionEscape {
+		t.Errorf("expected the unterminated tag to be logged, got %v", removals)
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const documentsSearchIndexFormat = "documents"
+const lunrSearchIndexFormat = "lunr"
+
+// lunrVersion version of lunr the serialized index is compatible with
+const lunrVersion = "2.3.9"
+
+// lunr BM25 parameters
+const lunrK1 = 1.2
+const lunrB = 0.75
+
+const defaultSearchIndexExcerptWords = 50
+
+var searchIndexFields = []string{"title", "tags", "summary", "body"}
+
+var defaultSearchIndexFieldBoosts = map[string]float64{"title": 10, "tags": 5, "summary": 2, "body": 1}
+
+// lunrSeparatorRegexp matches lunr.tokenizer.separator
+var lunrSeparatorRegexp = regexp.MustCompile(`[\s\-]+`)
+
+// lunrTrimmerRegexp matches what lunr.trimmer removes from each end of a token
+var lunrTrimmerRegexp = regexp.MustCompile(`^\W+|\W+$`)
+
+// SearchDocument a post as seen by the site search
+type SearchDocument struct {
+	ID      string   `json:"id"`
+	Title   string   `json:"title"`
+	Slug    string   `json:"slug"`
+	Tags    []string `json:"tags"`
+	Summary string   `json:"summary"`
+	Excerpt string   `json:"excerpt"`
+	URL     string   `json:"url"`
+}
+
+// LunrIndex lunr 2.x serialized index. load with lunr.Index.load(data.index)
+type LunrIndex struct {
+	Version       string          `json:"version"`
+	Fields        []string        `json:"fields"`
+	FieldVectors  [][]interface{} `json:"fieldVectors"`
+	InvertedIndex [][]interface{} `json:"invertedIndex"`
+	Pipeline      []string        `json:"pipeline"`
+}
+
+// LunrSearchIndex a prebuilt index and the documents its refs point to
+type LunrSearchIndex struct {
+	Index     LunrIndex        `json:"index"`
+	Documents []SearchDocument `json:"documents"`
+}
+
+// getSearchIndexFieldBoosts outputs.search.fieldBoosts on top of the default boosts. e.g. {title: 10, tags: 5, summary: 2, body: 1}
+func getSearchIndexFieldBoosts() (map[string]float64, error) {
+	boosts := make(map[string]float64)
+	for field, boost := range defaultSearchIndexFieldBoosts {
+		boosts[field] = boost
+	}
+	for field, boost := range getConfig().Outputs.Search.FieldBoosts {
+		if !containsFold(searchIndexFields, field) {
+			return nil, fmt.Errorf("outputs.search.fieldBoosts: unknown field %q", field)
+		}
+		boosts[strings.ToLower(field)] = boost
+	}
+	return boosts, nil
+}
+
+func getSearchIndexExcerptWords() int {
+	if words := getConfig().Outputs.Search.ExcerptWords; words > 0 {
+		return words
+	}
+	return defaultSearchIndexExcerptWords
+}
+
+// getPlainText post body without code blocks or markdown syntax
+func getPlainText(markdown string) string {
+	words := make([]string, 0)
+	for _, segment := range splitMarkdownFences(markdown) {
+		if segment.Fenced {
+			continue
+		}
+		for _, line := range strings.Split(segment.Text, "\n") {
+			line = strings.TrimLeft(strings.TrimSpace(line), "#>-+|")
+			words = append(words, strings.Fields(stripInlineMarkdown(line))...)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func getExcerpt(text string, wordCount int) string {
+	words := strings.Fields(text)
+	if len(words) <= wordCount {
+		return strings.Join(words, " ")
+	}
+	return strings.Join(words[:wordCount], " ") + "…"
+}
+
+func newSearchDocument(repoPost *RepoPost, outputTarget OutputTarget, excerptWords int) SearchDocument {
+	tags := repoPost.Tags
+	if tags == nil {
+		tags = make([]string, 0)
+	}
+	return SearchDocument{
+		ID:      repoPost.Repo.GetFullName(),
+		Title:   repoPost.Title,
+		Slug:    repoPost.Slug,
+		Tags:    tags,
+		Summary: repoPost.Summary,
+		Excerpt: getExcerpt(getPlainText(repoPost.MarkdownBody), excerptWords),
+		URL:     getPostURLPath(repoPost, outputTarget),
+	}
+}
+
+// lunrTokenize splits and trims text the way the default lunr builder pipeline does, without stemming
+func lunrTokenize(text string) []string {
+	tokens := make([]string, 0)
+	for _, token := range lunrSeparatorRegexp.Split(strings.ToLower(text), -1) {
+		token = lunrTrimmerRegexp.ReplaceAllString(token, "")
+		if token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+func roundLunrScore(score float64) float64 {
+	return math.Round(score*1000) / 1000
+}
+
+// buildLunrIndex scores every term in every field of the documents with lunr's BM25 so the index
+// can be loaded by lunr.Index.load without rebuilding it in the browser
+func buildLunrIndex(documents []SearchDocument, bodies []string, boosts map[string]float64) LunrIndex {
+	type fieldRef struct {
+		field string
+		ref   string
+	}
+
+	termFrequencies := make(map[fieldRef]map[string]int)
+	fieldLengths := make(map[fieldRef]int)
+	totalFieldLengths := make(map[string]int)
+	postings := make(map[string]map[string]map[string]bool)
+	fieldRefs := make([]fieldRef, 0)
+
+	for i, document := range documents {
+		values := map[string]string{
+			"title":   document.Title,
+			"tags":    strings.Join(document.Tags, " "),
+			"summary": document.Summary,
+			"body":    bodies[i],
+		}
+		for _, field := range searchIndexFields {
+			ref := fieldRef{field, document.ID}
+			fieldRefs = append(fieldRefs, ref)
+			tokens := lunrTokenize(values[field])
+			fieldLengths[ref] = len(tokens)
+			totalFieldLengths[field] += len(tokens)
+			termFrequencies[ref] = make(map[string]int)
+			for _, token := range tokens {
+				termFrequencies[ref][token]++
+				if postings[token] == nil {
+					postings[token] = make(map[string]map[string]bool)
+				}
+				if postings[token][field] == nil {
+					postings[token][field] = make(map[string]bool)
+				}
+				postings[token][field][document.ID] = true
+			}
+		}
+	}
+
+	terms := make([]string, 0, len(postings))
+	for term := range postings {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	termIndexes := make(map[string]int)
+	for i, term := range terms {
+		termIndexes[term] = i
+	}
+
+	index := LunrIndex{
+		Version:       lunrVersion,
+		Fields:        searchIndexFields,
+		FieldVectors:  make([][]interface{}, 0, len(fieldRefs)),
+		InvertedIndex: make([][]interface{}, 0, len(terms)),
+		Pipeline:      make([]string, 0),
+	}
+
+	for _, term := range terms {
+		posting := map[string]interface{}{"_index": termIndexes[term]}
+		for _, field := range searchIndexFields {
+			refs := make(map[string]interface{})
+			for ref := range postings[term][field] {
+				refs[ref] = map[string]interface{}{}
+			}
+			posting[field] = refs
+		}
+		index.InvertedIndex = append(index.InvertedIndex, []interface{}{term, posting})
+	}
+
+	documentCount := float64(len(documents))
+	for _, ref := range fieldRefs {
+		averageFieldLength := float64(totalFieldLengths[ref.field]) / documentCount
+		fieldTerms := make([]string, 0, len(termFrequencies[ref]))
+		for term := range termFrequencies[ref] {
+			fieldTerms = append(fieldTerms, term)
+		}
+		sort.Slice(fieldTerms, func(i, j int) bool { return termIndexes[fieldTerms[i]] < termIndexes[fieldTerms[j]] })
+
+		vector := make([]float64, 0, len(fieldTerms)*2)
+		for _, term := range fieldTerms {
+			documentsWithTerm := 0
+			for _, refs := range postings[term] {
+				documentsWithTerm += len(refs)
+			}
+			idf := math.Log(1 + math.Abs((documentCount-float64(documentsWithTerm)+0.5)/(float64(documentsWithTerm)+0.5)))
+			tf := float64(termFrequencies[ref][term])
+			score := idf * ((lunrK1 + 1) * tf) / (lunrK1*(1-lunrB+lunrB*(float64(fieldLengths[ref])/averageFieldLength)) + tf)
+			vector = append(vector, float64(termIndexes[term]), roundLunrScore(score*boosts[ref.field]))
+		}
+		index.FieldVectors = append(index.FieldVectors, []interface{}{ref.field + "/" + ref.ref, vector})
+	}
+	return index
+}
+
+// writeSearchIndex writes the posts as a list of search documents or as a lunr index along with its documents
+func writeSearchIndex(w io.Writer, repoPosts []RepoPost, format string) error {
+	outputTarget, err := getOutputTarget()
+	if err != nil {
+		return err
+	}
+
+	excerptWords := getSearchIndexExcerptWords()
+	documents := make([]SearchDocument, 0, len(repoPosts))
+	bodies := make([]string, 0, len(repoPosts))
+	for i := range repoPosts {
+		documents = append(documents, newSearchDocument(&repoPosts[i], outputTarget, excerptWords))
+		bodies = append(bodies, getPlainText(repoPosts[i].MarkdownBody))
+	}
+
+	var searchIndex interface{}
+	switch format {
+	case "", documentsSearchIndexFormat:
+		searchIndex = documents
+	case lunrSearchIndexFormat:
+		boosts, err := getSearchIndexFieldBoosts()
+		if err != nil {
+			log.Printf("getSearchIndexFieldBoosts() failed\n")
+			return err
+		}
+		searchIndex = LunrSearchIndex{Index: buildLunrIndex(documents, bodies, boosts), Documents: documents}
+	default:
+		return fmt.Errorf("unknown search index format %q", format)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(searchIndex)
+}
+
+// createSearchIndexForUser writes the search index to path or stdout when path is empty
+func createSearchIndexForUser(username string, path string, format string) error {
+	repoPosts, err := getRepoPosts(username)
+	if err != nil {
+		log.Printf("getRepoPosts(%s) failed\n", username)
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			log.Printf("os.Create(%s) failed\n", path)
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return writeSearchIndex(w, repoPosts, format)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+const testSearchLambdaMarkdown = "## Usage\n\nDeploy a **function** with [sam](https://aws.amazon.com/serverless/sam/).\n\n```sh\nnpx cdk synth\n```\n"
+
+func TestWriteSearchIndexDocuments(t *testing.T) {
+	var buf bytes.Buffer
+	repoPosts := []RepoPost{newTestRepoPost("lambda-playground", "Lambda", testSearchLambdaMarkdown, "aws", "serverless"),
+		newTestRepoPost("flexbox-playground", "Flexbox", "Layout with flexbox.\n", "css")}
+	if err := writeSearchIndex(&buf, repoPosts, documentsSearchIndexFormat); err != nil {
+		t.Fatal(err)
+	}
+	var documents []SearchDocument
+	if err := json.Unmarshal(buf.Bytes(), &documents); err != nil {
+		t.Fatal(err)
+	}
+	if len(documents) != 2 {
+		t.Fatalf("expected 2 documents, got %d", len(documents))
+	}
+	want := SearchDocument{ID: "pfeilbr/lambda-playground", Title: "Lambda", Slug: "lambda-playground", Tags: []string{"aws", "serverless"},
+		Summary: "learn Lambda", Excerpt: "Usage Deploy a function with sam.", URL: "/post/lambda-playground/"}
+	got := documents[0]
+	if got.ID != want.ID || got.Excerpt != want.Excerpt || got.URL != want.URL || len(got.Tags) != 2 {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestGetPostURLPath(t *testing.T) {
+	os.Setenv("POST_URL_PATTERN", "/{year}/{month}/{slug}/")
+	defer os.Unsetenv("POST_URL_PATTERN")
+	repoPost := newTestRepoPost("lambda-playground", "Lambda", testSearchLambdaMarkdown, "aws", "serverless")
+	repoPost.Repo.CreatedAt = &github.Timestamp{Time: time.Date(2020, 3, 4, 0, 0, 0, 0, time.UTC)}
+	if url := getPostURLPath(&repoPost, outputTargets[hugoTarget]); url != "/2020/03/lambda-playground/" {
+		t.Errorf("got %q", url)
+	}
+}
+
+func TestBuildLunrIndex(t *testing.T) {
+	os.Setenv("SEARCH_INDEX_FIELD_BOOSTS", "title=20")
+	defer os.Unsetenv("SEARCH_INDEX_FIELD_BOOSTS")
+
+	var buf bytes.Buffer
+	repoPosts := []RepoPost{newTestRepoPost("lambda-playground", "Lambda", testSearchLambdaMarkdown, "aws", "serverless"),
+		newTestRepoPost("flexbox-playground", "Flexbox", "Layout with flexbox.\n", "css")}
+	if err := writeSearchIndex(&buf, repoPosts, lunrSearchIndexFormat); err != nil {
+		t.Fatal(err)
+	}
+	var searchIndex LunrSearchIndex
+	if err := json.Unmarshal(buf.Bytes(), &searchIndex); err != nil {
+		t.Fatal(err)
+	}
+	index := searchIndex.Index
+
+	if index.Version != lunrVersion || len(index.Fields) != 4 || len(index.FieldVectors) != 8 {
+		t.Fatalf("unexpected index %+v", index)
+	}
+
+	terms := make(map[string]map[string]interface{})
+	for i, entry := range index.InvertedIndex {
+		posting := entry[1].(map[string]interface{})
+		if int(posting["_index"].(float64)) != i {
+			t.Errorf("expected term %v at %d", entry[0], i)
+		}
+		terms[entry[0].(string)] = posting
+	}
+	if _, ok := terms["function"]["body"].(map[string]interface{})["pfeilbr/lambda-playground"]; !ok {
+		t.Errorf("expected function in lambda body, got %v", terms["function"])
+	}
+	if _, ok := terms["synth"]; ok {
+		t.Errorf("expected code blocks to be skipped, got %v", terms["synth"])
+	}
+
+	title := index.FieldVectors[0]
+	if title[0] != "title/pfeilbr/lambda-playground" {
+		t.Fatalf("unexpected field ref %v", title[0])
+	}
+	// lambda is in the title and summary of 1 of 2 documents so lunr counts it twice. tf 1 and average length 1 leave just the idf
+	vector := title[1].([]interface{})
+	if score, want := vector[1].(float64), roundLunrScore(math.Log(1+0.5/2.5)*20); score != want {
+		t.Errorf("expected title score %v, got %v", want, score)
+	}
+}
+
+func TestGetSearchIndexFieldBoostsInvalid(t *testing.T) {
+	os.Setenv("SEARCH_INDEX_FIELD_BOOSTS", "author=2")
+	defer os.Unsetenv("SEARCH_INDEX_FIELD_BOOSTS")
+	if _, err := getSearchIndexFieldBoosts(); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultSlugHistoryFileName = "slug-history.json"
+
+const netlifyRedirectsFormat = "netlify"
+const nginxRedirectsFormat = "nginx"
+
+// SlugHistory every slug a repo's post has been published with keyed by repo ID so renamed repos keep their history
+type SlugHistory map[string]*SlugHistoryEntry
+
+// SlugHistoryEntry the slugs of a repo's post in the order they were first published
+type SlugHistoryEntry struct {
+	Repo  string   `json:"repo"`
+	Slugs []string `json:"slugs"`
+}
+
+// Redirect an old post URL and the URL it moved to
+type Redirect struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// getSlugHistoryPath every profile has a history of its own unless outputs.slugHistoryFile says otherwise,
+// e.g. slug-history-blog.json for -profile blog
+func getSlugHistoryPath() string {
+	if path := getConfig().Outputs.SlugHistoryFile; path != "" {
+		return path
+	}
+	if profile != "" {
+		return strings.TrimSuffix(defaultSlugHistoryFileName, ".json") + "-" + profile + ".json"
+	}
+	return defaultSlugHistoryFileName
+}
+
+func getRepoIDKey(repoPost *RepoPost) string {
+	return strconv.FormatInt(repoPost.Repo.GetID(), 10)
+}
+
+func loadSlugHistory(path string) (SlugHistory, error) {
+	history := make(SlugHistory)
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &history); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return history, nil
+}
+
+func saveSlugHistory(path string, history SlugHistory) error {
+	b, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeSiteFile(filepath.Dir(path), filepath.Base(path), append(b, '\n'))
+}
+
+// record adds the current slug of each post to its repo's history
+func (history SlugHistory) record(repoPosts []RepoPost) {
+	for i := range repoPosts {
+		repoPost := &repoPosts[i]
+		if repoPost.Repo.ID == nil {
+			continue
+		}
+		key := getRepoIDKey(repoPost)
+		entry, ok := history[key]
+		if !ok {
+			entry = &SlugHistoryEntry{Slugs: make([]string, 0)}
+			history[key] = entry
+		}
+		entry.Repo = repoPost.Repo.GetFullName()
+		if !contains(entry.Slugs, repoPost.Slug) {
+			entry.Slugs = append(entry.Slugs, repoPost.Slug)
+		}
+	}
+}
+
+func contains(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// getPostAliases the URLs a post was published at before its slug changed.
+// URLs the slug isn't part of, e.g. with a {name} postURLPattern, are the post's own URL and left out
+func getPostAliases(repoPost *RepoPost, history SlugHistory, outputTarget OutputTarget) []string {
+	if repoPost.Repo.ID == nil {
+		return nil
+	}
+	entry, ok := history[getRepoIDKey(repoPost)]
+	if !ok {
+		return nil
+	}
+
+	postURLPath := getPostURLPath(repoPost, outputTarget)
+	aliases := make([]string, 0)
+	for _, slug := range entry.Slugs {
+		previous := *repoPost
+		previous.Slug = slug
+		alias := getPostURLPath(&previous, outputTarget)
+		if alias == postURLPath || contains(aliases, alias) {
+			continue
+		}
+		aliases = append(aliases, alias)
+	}
+	if len(aliases) == 0 {
+		return nil
+	}
+	return aliases
+}
+
+// recordPublishedSlugs remembers the slug of every post so the post keeps its old URLs as aliases when its slug changes
+func recordPublishedSlugs(repoPosts []RepoPost) error {
+	path := getSlugHistoryPath()
+	history, err := loadSlugHistory(path)
+	if err != nil {
+		log.Printf("loadSlugHistory(%s) failed\n", path)
+		return err
+	}
+	history.record(repoPosts)
+	if err := saveSlugHistory(path, history); err != nil {
+		log.Printf("saveSlugHistory(%s) failed\n", path)
+		return err
+	}
+	return nil
+}
+
+func getRedirects(repoPosts []RepoPost, outputTarget OutputTarget) []Redirect {
+	redirects := make([]Redirect, 0)
+	for i := range repoPosts {
+		to := getPostURLPath(&repoPosts[i], outputTarget)
+		for _, alias := range repoPosts[i].Aliases {
+			redirects = append(redirects, Redirect{From: alias, To: to})
+		}
+	}
+	sort.Slice(redirects, func(i, j int) bool { return redirects[i].From < redirects[j].From })
+	return redirects
+}
+
+// writeRedirects writes a netlify _redirects file or entries for an nginx map block. e.g.
+//
+//	map $uri $redirect_uri {
+//	    include redirects.map;
+//	}
+func writeRedirects(w io.Writer, redirects []Redirect, format string) error {
+	line := map[string]string{netlifyRedirectsFormat: "%s %s 301\n", nginxRedirectsFormat: "%s %s;\n"}[format]
+	if line == "" {
+		return fmt.Errorf("unknown redirects format %q", format)
+	}
+	for _, redirect := range redirects {
+		if _, err := fmt.Fprintf(w, line, redirect.From, redirect.To); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createRedirectsForUser(username string, path string, format string) error {
+	repoPosts, err := getRepoPosts(username)
+	if err != nil {
+		log.Printf("getRepoPosts(%s) failed\n", username)
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			log.Printf("os.Create(%s) failed\n", path)
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	outputTarget, err := getOutputTarget()
+	if err != nil {
+		return err
+	}
+	return writeRedirects(w, getRedirects(repoPosts, outputTarget), format)
+}
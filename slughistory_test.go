@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSlugHistoryAliases(t *testing.T) {
+	directory, err := ioutil.TempDir("", "slugs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+	os.Setenv("SLUG_HISTORY_FILE", filepath.Join(directory, "slug-history.json"))
+	defer os.Unsetenv("SLUG_HISTORY_FILE")
+
+	id := int64(42)
+	repo := newTestRepo("aws-lambda-playground")
+	repo.ID = &id
+	repoPost := RepoPost{Repo: repo, Slug: "aws-lambda"}
+	if err := recordPublishedSlugs([]RepoPost{repoPost}); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := loadSlugHistory(getSlugHistoryPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aliases := getPostAliases(&repoPost, history, outputTargets[hugoTarget]); aliases != nil {
+		t.Errorf("expected no aliases for an unchanged slug, got %v", aliases)
+	}
+
+	// a casing mapping changed the slug
+	repoPost.Slug = "aws-lambda-functions"
+	aliases := getPostAliases(&repoPost, history, outputTargets[hugoTarget])
+	if len(aliases) != 1 || aliases[0] != "/post/aws-lambda/" {
+		t.Fatalf("expected the old URL as an alias, got %v", aliases)
+	}
+	repoPost.Aliases = aliases
+
+	frontMatter, err := marshalFrontMatter(getHugoFrontMatter(&repoPost), tomlFrontMatterFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(frontMatter, `aliases = ["/post/aws-lambda/"]`) {
+		t.Errorf("expected aliases in front matter %s", frontMatter)
+	}
+
+	if err := recordPublishedSlugs([]RepoPost{repoPost}); err != nil {
+		t.Fatal(err)
+	}
+	history, _ = loadSlugHistory(getSlugHistoryPath())
+	if slugs := history["42"].Slugs; len(slugs) != 2 || slugs[1] != "aws-lambda-functions" {
+		t.Errorf("unexpected history %v", slugs)
+	}
+
+	var buf bytes.Buffer
+	redirects := getRedirects([]RepoPost{repoPost}, outputTargets[hugoTarget])
+	if err := writeRedirects(&buf, redirects, netlifyRedirectsFormat); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "/post/aws-lambda/ /post/aws-lambda-functions/ 301\n" {
+		t.Errorf("unexpected netlify redirects %q", buf.String())
+	}
+	buf.Reset()
+	if err := writeRedirects(&buf, redirects, nginxRedirectsFormat); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "/post/aws-lambda/ /post/aws-lambda-functions/;\n" {
+		t.Errorf("unexpected nginx redirects %q", buf.String())
+	}
+	if err := writeRedirects(&buf, nil, "apache"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestGetSlugHistoryPathProfile(t *testing.T) {
+	profile = "blog"
+	defer func() { profile = "" }()
+	if path := getSlugHistoryPath(); path != "slug-history-blog.json" {
+		t.Errorf("expected a history per profile, got %s", path)
+	}
+}
+
+func TestSlugHistoryAliasesWithoutSlugInURL(t *testing.T) {
+	withTestConfig(t, "outputs:\n  postURLPattern: /{name}/\n")
+	id := int64(42)
+	repo := newTestRepo("aws-lambda-playground")
+	repo.ID = &id
+	repoPost := RepoPost{Repo: repo, Slug: "aws-lambda-functions"}
+	history := SlugHistory{"42": {Repo: "pfeilbr/aws-lambda-playground", Slugs: []string{"aws-lambda", "lambda", "aws-lambda-functions"}}}
+
+	// every slug gives the post's own URL, which would redirect to itself
+	if aliases := getPostAliases(&repoPost, history, outputTargets[hugoTarget]); aliases != nil {
+		t.Errorf("expected no aliases, got %v", aliases)
+	}
+
+	withTestConfig(t, "outputs:\n  postURLPattern: /{name}/{slug}/\n")
+	aliases := getPostAliases(&repoPost, history, outputTargets[hugoTarget])
+	if len(aliases) != 2 || aliases[0] != "/aws-lambda-playground/aws-lambda/" || aliases[1] != "/aws-lambda-playground/lambda/" {
+		t.Errorf("expected the old URLs once each, got %v", aliases)
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const socialCardsNextToPost = "post"
+const socialCardsStatic = "static"
+
+const defaultLanguageColor = "#6e7681"
+
+// SocialCardLayout sizes and colours of a social card. set with outputs.socialCards.layout
+type SocialCardLayout struct {
+	Width         int     `json:"width" yaml:"width"`
+	Height        int     `json:"height" yaml:"height"`
+	Padding       int     `json:"padding" yaml:"padding"`
+	Background    string  `json:"background" yaml:"background"`
+	Foreground    string  `json:"foreground" yaml:"foreground"`
+	Muted         string  `json:"muted" yaml:"muted"`
+	AccentHeight  int     `json:"accentHeight" yaml:"accentHeight"`
+	TitleSize     float64 `json:"titleSize" yaml:"titleSize"`
+	TitleMaxLines int     `json:"titleMaxLines" yaml:"titleMaxLines"`
+	TagSize       float64 `json:"tagSize" yaml:"tagSize"`
+	FooterSize    float64 `json:"footerSize" yaml:"footerSize"`
+	ShowStars     bool    `json:"showStars" yaml:"showStars"`
+}
+
+var defaultSocialCardLayout = SocialCardLayout{
+	Width:         1200,
+	Height:        630,
+	Padding:       80,
+	Background:    "#0d1117",
+	Foreground:    "#f0f6fc",
+	Muted:         "#8b949e",
+	AccentHeight:  16,
+	TitleSize:     72,
+	TitleMaxLines: 3,
+	TagSize:       32,
+	FooterSize:    32,
+}
+
+// languageColors github linguist colours of common languages
+var languageColors = map[string]string{
+	"c":                "#555555",
+	"c#":               "#178600",
+	"c++":              "#f34b7d",
+	"css":              "#563d7c",
+	"dockerfile":       "#384d54",
+	"go":               "#00add8",
+	"hcl":              "#844fba",
+	"html":             "#e34c26",
+	"java":             "#b07219",
+	"javascript":       "#f1e05a",
+	"jupyter notebook": "#da5b0b",
+	"kotlin":           "#a97bff",
+	"lua":              "#000080",
+	"php":              "#4f5d95",
+	"python":           "#3572a5",
+	"ruby":             "#701516",
+	"rust":             "#dea584",
+	"shell":            "#89e051",
+	"swift":            "#f05138",
+	"typescript":       "#3178c6",
+	"vue":              "#41b883",
+}
+
+// parseHexColor e.g. #00add8
+func parseHexColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid colour %q", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid colour %q", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+func getLanguageColor(language string) string {
+	if c, ok := languageColors[strings.ToLower(language)]; ok {
+		return c
+	}
+	return defaultLanguageColor
+}
+
+func newFontFace(ttf []byte, size float64) (font.Face, error) {
+	f, err := opentype.Parse(ttf)
+	if err != nil {
+		return nil, err
+	}
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+// wrapText splits text into lines no wider than width. the last of maxLines lines ends with an ellipsis when text doesn't fit
+func wrapText(face font.Face, text string, width int, maxLines int) []string {
+	lines := make([]string, 0)
+	line := ""
+	for _, word := range strings.Fields(text) {
+		candidate := strings.TrimSpace(line + " " + word)
+		if line != "" && font.MeasureString(face, candidate).Ceil() > width {
+			lines = append(lines, line)
+			line = word
+			continue
+		}
+		line = candidate
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+
+	if maxLines > 0 && len(lines) > maxLines {
+		lines = lines[:maxLines]
+		last := lines[maxLines-1] + "…"
+		for font.MeasureString(face, last).Ceil() > width && strings.Contains(last, " ") {
+			last = last[:strings.LastIndex(last, " ")] + "…"
+		}
+		lines[maxLines-1] = last
+	}
+	return lines
+}
+
+func drawText(dst draw.Image, face font.Face, c color.Color, x int, baseline int, text string) {
+	drawer := font.Drawer{Dst: dst, Src: image.NewUniform(c), Face: face, Dot: fixed.P(x, baseline)}
+	drawer.DrawString(text)
+}
+
+// renderSocialCard a PNG with the post title, tags, a bar in the colour of the repo's language and the repo full name
+func renderSocialCard(repoPost *RepoPost, layout SocialCardLayout) ([]byte, error) {
+	background, err := parseHexColor(layout.Background)
+	if err != nil {
+		return nil, err
+	}
+	foreground, err := parseHexColor(layout.Foreground)
+	if err != nil {
+		return nil, err
+	}
+	muted, err := parseHexColor(layout.Muted)
+	if err != nil {
+		return nil, err
+	}
+	accent, err := parseHexColor(getLanguageColor(repoPost.Repo.GetLanguage()))
+	if err != nil {
+		return nil, err
+	}
+
+	titleFace, err := newFontFace(gobold.TTF, layout.TitleSize)
+	if err != nil {
+		return nil, err
+	}
+	defer titleFace.Close()
+	tagFace, err := newFontFace(goregular.TTF, layout.TagSize)
+	if err != nil {
+		return nil, err
+	}
+	defer tagFace.Close()
+	footerFace, err := newFontFace(goregular.TTF, layout.FooterSize)
+	if err != nil {
+		return nil, err
+	}
+	defer footerFace.Close()
+
+	card := image.NewRGBA(image.Rect(0, 0, layout.Width, layout.Height))
+	draw.Draw(card, card.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	draw.Draw(card, image.Rect(0, 0, layout.Width, layout.AccentHeight), image.NewUniform(accent), image.Point{}, draw.Src)
+
+	textWidth := layout.Width - 2*layout.Padding
+	y := layout.AccentHeight + layout.Padding
+	titleLineHeight := titleFace.Metrics().Height.Ceil()
+	for _, line := range wrapText(titleFace, repoPost.Title, textWidth, layout.TitleMaxLines) {
+		y += titleLineHeight
+		drawText(card, titleFace, foreground, layout.Padding, y, line)
+	}
+
+	if len(repoPost.Tags) > 0 {
+		tags := make([]string, 0, len(repoPost.Tags))
+		for _, tag := range repoPost.Tags {
+			tags = append(tags, "#"+tag)
+		}
+		y += tagFace.Metrics().Height.Ceil() * 3 / 2
+		drawText(card, tagFace, muted, layout.Padding, y, wrapText(tagFace, strings.Join(tags, "  "), textWidth, 1)[0])
+	}
+
+	footerBaseline := layout.Height - layout.Padding
+	footer := repoPost.Repo.GetFullName()
+	if language := repoPost.Repo.GetLanguage(); language != "" {
+		footer += "  ·  " + language
+	}
+	drawText(card, footerFace, foreground, layout.Padding, footerBaseline, footer)
+	if layout.ShowStars {
+		stars := fmt.Sprintf("%d stars", repoPost.Repo.GetStargazersCount())
+		drawText(card, footerFace, muted, layout.Width-layout.Padding-font.MeasureString(footerFace, stars).Ceil(), footerBaseline, stars)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, card); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// getSocialCardFileName e.g. generated-lambda-playground.png next to the post or lambda-playground.png in the static directory
+func getSocialCardFileName(repoPost *RepoPost) string {
+	if getConfig().Outputs.SocialCards.Mode == socialCardsStatic {
+		return repoPost.Slug + ".png"
+	}
+	return strings.TrimSuffix(repoPost.PostFileName, filepath.Ext(repoPost.PostFileName)) + ".png"
+}
+
+// getSocialCardImages the front matter images of a post when outputs.socialCards.mode is set
+func getSocialCardImages(repoPost *RepoPost) []string {
+	config := getConfig().Outputs.SocialCards
+	if config.Mode != socialCardsNextToPost && config.Mode != socialCardsStatic {
+		return nil
+	}
+	return []string{config.URLPrefix + filepath.ToSlash(filepath.Base(getSocialCardFileName(repoPost)))}
+}
+
+// getSocialCardDirectory the directory cards of posts in destinationDirectory are written to, empty when cards are off
+func getSocialCardDirectory(destinationDirectory string) (string, error) {
+	config := getConfig().Outputs.SocialCards
+	switch config.Mode {
+	case "":
+		return "", nil
+	case socialCardsNextToPost:
+		return destinationDirectory, nil
+	case socialCardsStatic:
+		if config.StaticDirectory == "" {
+			return "", fmt.Errorf("outputs.socialCards.staticDirectory is required when the mode is static")
+		}
+		return config.StaticDirectory, nil
+	}
+	return "", fmt.Errorf("outputs.socialCards.mode: unknown mode %q", config.Mode)
+}
+
+// getSocialCardManifestName the card's path relative to destinationDirectory, which is how the generated posts state records it.
+// e.g. generated-lambda-playground.png or ../../static/images/cards/lambda-playground.png
+func getSocialCardManifestName(repoPost *RepoPost, directory string, destinationDirectory string) string {
+	path := filepath.Join(directory, getSocialCardFileName(repoPost))
+	absolutePath, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	absoluteDestinationDirectory, err := filepath.Abs(destinationDirectory)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	name, err := filepath.Rel(absoluteDestinationDirectory, absolutePath)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(name)
+}
+
+// writeSocialCards renders a card for every post next to the post file or into outputs.socialCards.staticDirectory
+// and records it in state so prune and publish handle cards like the posts they belong to
+func writeSocialCards(repoPosts []RepoPost, destinationDirectory string, state *GeneratedPostsState) error {
+	directory, err := getSocialCardDirectory(destinationDirectory)
+	if err != nil || directory == "" {
+		return err
+	}
+
+	layout := getConfig().Outputs.SocialCards.Layout
+	for i := range repoPosts {
+		card, err := renderSocialCard(&repoPosts[i], layout)
+		if err != nil {
+			log.Printf("renderSocialCard(%s) failed\n", repoPosts[i].Repo.GetName())
+			return err
+		}
+		if err := writeSiteFile(directory, getSocialCardFileName(&repoPosts[i]), card); err != nil {
+			return err
+		}
+		state.Files[getSocialCardManifestName(&repoPosts[i], directory, destinationDirectory)] = GeneratedFile{
+			Repo:   repoPosts[i].Repo.GetFullName(),
+			SHA256: getContentHash(string(card)),
+		}
+	}
+	return nil
+}
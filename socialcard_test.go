@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+func TestRenderSocialCard(t *testing.T) {
+	repoPost := newTestRepoPost("lambda-playground", "AWS Lambda with a title long enough to wrap onto a second line", "", "aws", "serverless")
+	repoPost.Slug = "aws-lambda"
+	repoPost.Repo.Language = github.String("Go")
+	repoPost.Repo.StargazersCount = github.Int(12)
+	layout := defaultSocialCardLayout
+	layout.ShowStars = true
+	b, err := renderSocialCard(&repoPost, layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	card, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.Bounds().Dx() != 1200 || card.Bounds().Dy() != 630 {
+		t.Errorf("unexpected size %v", card.Bounds())
+	}
+	if got := color.RGBAModel.Convert(card.At(0, 0)).(color.RGBA); got != (color.RGBA{0x00, 0xad, 0xd8, 0xff}) {
+		t.Errorf("expected the go language colour accent, got %v", got)
+	}
+
+	textPixels := 0
+	for y := layout.AccentHeight + layout.Padding; y < 300; y++ {
+		for x := layout.Padding; x < layout.Width-layout.Padding; x++ {
+			if r, _, _, _ := card.At(x, y).RGBA(); r>>8 > 0x80 {
+				textPixels++
+			}
+		}
+	}
+	if textPixels == 0 {
+		t.Error("expected the title to be drawn")
+	}
+}
+
+func TestWriteSocialCards(t *testing.T) {
+	directory, err := ioutil.TempDir("", "cards")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(directory)
+
+	os.Setenv("SOCIAL_CARDS", socialCardsStatic)
+	defer os.Unsetenv("SOCIAL_CARDS")
+	os.Setenv("SOCIAL_CARD_STATIC_DIRECTORY", filepath.Join(directory, "static", "images", "cards"))
+	defer os.Unsetenv("SOCIAL_CARD_STATIC_DIRECTORY")
+	os.Setenv("SOCIAL_CARD_URL_PREFIX", "/images/cards/")
+	defer os.Unsetenv("SOCIAL_CARD_URL_PREFIX")
+
+	repoPost := newTestRepoPost("lambda-playground", "AWS Lambda with a title long enough to wrap onto a second line", "", "aws", "serverless")
+	repoPost.Slug = "aws-lambda"
+	repoPost.Repo.Language = github.String("Go")
+	repoPost.Repo.StargazersCount = github.Int(12)
+	repoPost.Images = getSocialCardImages(&repoPost)
+	if len(repoPost.Images) != 1 || repoPost.Images[0] != "/images/cards/aws-lambda.png" {
+		t.Errorf("unexpected images %v", repoPost.Images)
+	}
+	frontMatter, err := marshalFrontMatter(newFrontMatter(&repoPost), tomlFrontMatterFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(frontMatter, `images = ["/images/cards/aws-lambda.png"]`) {
+		t.Errorf("expected images in front matter %s", frontMatter)
+	}
+
+	state := &GeneratedPostsState{Files: make(map[string]GeneratedFile)}
+	if err := writeSocialCards([]RepoPost{repoPost}, filepath.Join(directory, "content", "post"), state); err != nil {
+		t.Fatal(err)
+	}
+	if !fileExists(filepath.Join(directory, "static", "images", "cards", "aws-lambda.png")) {
+		t.Error("expected the card in the static directory")
+	}
+	if card, ok := state.Files["../../static/images/cards/aws-lambda.png"]; !ok || card.Repo != "pfeilbr/lambda-playground" || card.SHA256 == "" {
+		t.Errorf("expected the card in the manifest, got %+v", state.Files)
+	}
+
+	os.Setenv("SOCIAL_CARDS", socialCardsNextToPost)
+	if name := getSocialCardFileName(&repoPost); name != "generated-lambda-playground.png" {
+		t.Errorf("unexpected card file name %s", name)
+	}
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+	"github.com/google/go-github/github"
+	log "github.com/sirupsen/logrus"
+)
+
+// TagRule adds and removes tags of posts whose repo matches every condition set on the rule.
+// rules run in order after the other tags are picked, so a rule can remove a tag an earlier rule added
+type TagRule struct {
+	Name           string   `json:"name,omitempty" yaml:"name,omitempty"`
+	NameRegex      string   `json:"nameRegex,omitempty" yaml:"nameRegex,omitempty"`
+	Language       string   `json:"language,omitempty" yaml:"language,omitempty"`
+	Topics         []string `json:"topics,omitempty" yaml:"topics,omitempty"`
+	Files          []string `json:"files,omitempty" yaml:"files,omitempty"`
+	ReadmeKeywords []string `json:"readmeKeywords,omitempty" yaml:"readmeKeywords,omitempty"`
+	Dependencies   []string `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
+	Add            []string `json:"add,omitempty" yaml:"add,omitempty"`
+	Remove         []string `json:"remove,omitempty" yaml:"remove,omitempty"`
+}
+
+// PostTagExplanation where a tag of a post came from. e.g. tags.autoIfInRepoName or tags.rules[2] (docker)
+type PostTagExplanation struct {
+	Tag       string   `json:"tag"`
+	Sources   []string `json:"sources"`
+	RemovedBy string   `json:"removedBy,omitempty"`
+}
+
+// PostTagsReport the tags of a repo's post and where each one came from
+type PostTagsReport struct {
+	Repo string               `json:"repo"`
+	Tags []PostTagExplanation `json:"tags"`
+}
+
+// DependencyManifest a file at the root of a repo that lists its dependencies
+type DependencyManifest struct {
+	Path              string
+	ParseDependencies func(contents string) ([]string, error)
+}
+
+var dependencyManifests = []DependencyManifest{
+	{"package.json", parsePackageJSONDependencies},
+	{"go.mod", parseGoModDependencies},
+	{"requirements.txt", parseRequirementsDependencies},
+	{"Cargo.toml", parseCargoDependencies},
+}
+
+var requirementNameRegexp = regexp.MustCompile(`^[A-Za-z0-9._-]+`)
+
+func parsePackageJSONDependencies(contents string) ([]string, error) {
+	var manifest struct {
+		Dependencies         map[string]string `json:"dependencies"`
+		DevDependencies      map[string]string `json:"devDependencies"`
+		PeerDependencies     map[string]string `json:"peerDependencies"`
+		OptionalDependencies map[string]string `json:"optionalDependencies"`
+	}
+	if err := json.Unmarshal([]byte(contents), &manifest); err != nil {
+		return nil, err
+	}
+	dependencies := make([]string, 0)
+	for _, m := range []map[string]string{manifest.Dependencies, manifest.DevDependencies, manifest.PeerDependencies, manifest.OptionalDependencies} {
+		dependencies = append(dependencies, sortedKeys(m)...)
+	}
+	return dependencies, nil
+}
+
+// parseGoModDependencies the module paths of require directives, single line and block
+func parseGoModDependencies(contents string) ([]string, error) {
+	dependencies := make([]string, 0)
+	inRequireBlock := false
+	for _, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(strings.SplitN(line, "//", 2)[0])
+		switch {
+		case len(fields) == 0:
+		case inRequireBlock && fields[0] == ")":
+			inRequireBlock = false
+		case inRequireBlock:
+			dependencies = append(dependencies, fields[0])
+		case fields[0] == "require" && len(fields) > 1 && fields[1] == "(":
+			inRequireBlock = true
+		case fields[0] == "require" && len(fields) > 1:
+			dependencies = append(dependencies, fields[1])
+		}
+	}
+	return dependencies, nil
+}
+
+// parseRequirementsDependencies the package names of a pip requirements file, without versions, extras and markers
+func parseRequirementsDependencies(contents string) ([]string, error) {
+	dependencies := make([]string, 0)
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(strings.SplitN(line, "#", 2)[0])
+		// options like -r other.txt and -e .
+		if line == "" || strings.HasPrefix(line, "-") {
+			continue
+		}
+		if name := requirementNameRegexp.FindString(line); name != "" {
+			dependencies = append(dependencies, name)
+		}
+	}
+	return dependencies, nil
+}
+
+func parseCargoDependencies(contents string) ([]string, error) {
+	var manifest map[string]interface{}
+	if _, err := toml.Decode(contents, &manifest); err != nil {
+		return nil, err
+	}
+	dependencies := make([]string, 0)
+	for _, section := range []string{"dependencies", "dev-dependencies", "build-dependencies"} {
+		if table, ok := manifest[section].(map[string]interface{}); ok {
+			dependencies = append(dependencies, sortedKeys(table)...)
+		}
+	}
+	return dependencies, nil
+}
+
+// missingRepoFiles raw URLs that were 404 this run, shared by every profile.
+// they're only remembered for the run so a file the repo adds later is found next time
+var missingRepoFiles = make(map[string]bool)
+var missingRepoFilesMutex sync.Mutex
+
+func isMissingRepoFile(url string) bool {
+	missingRepoFilesMutex.Lock()
+	defer missingRepoFilesMutex.Unlock()
+	return missingRepoFiles[url]
+}
+
+func setMissingRepoFile(url string) {
+	missingRepoFilesMutex.Lock()
+	defer missingRepoFilesMutex.Unlock()
+	missingRepoFiles[url] = true
+}
+
+// getRepoFile the contents of a file of the repo and whether the repo has it. a 404 is remembered for the run
+func getRepoFile(repo *github.Repository, filePath string) (string, bool, error) {
+	url := getRawFileURL(repo, filePath)
+	if isMissingRepoFile(url) {
+		return "", false, nil
+	}
+	contents, err := getURLResponseBody(url, useCache)
+	if statusError, ok := err.(*URLStatusError); ok && statusError.StatusCode == http.StatusNotFound {
+		setMissingRepoFile(url)
+		return "", false, nil
+	}
+	if err != nil {
+		log.Printf("getURLResponseBody(%s) failed\n", url)
+		return "", false, err
+	}
+	return contents, true, nil
+}
+
+// tagRuleRepo what tag rules know about a repo. files and dependencies are only fetched when a rule asks for them.
+// a file that can't be fetched, e.g. on a 5xx or 429, is logged and counts as missing so the rules needing it are skipped
+type tagRuleRepo struct {
+	repo         *github.Repository
+	readme       string
+	files        map[string]bool
+	dependencies []string
+}
+
+func (r *tagRuleRepo) getFile(filePath string) (string, bool) {
+	contents, exists, err := getRepoFile(r.repo, filePath)
+	if err != nil {
+		log.Warnf("%s of %s can't be fetched, tag rules that need it are skipped: %v\n", filePath, r.repo.GetName(), err)
+	}
+	r.files[filePath] = exists
+	return contents, exists
+}
+
+func (r *tagRuleRepo) hasFile(filePath string) bool {
+	filePath = strings.TrimPrefix(filePath, "/")
+	if exists, ok := r.files[filePath]; ok {
+		return exists
+	}
+	_, exists := r.getFile(filePath)
+	return exists
+}
+
+func (r *tagRuleRepo) hasDependency(name string) bool {
+	if r.dependencies == nil {
+		r.dependencies = make([]string, 0)
+		for _, manifest := range dependencyManifests {
+			contents, exists := r.getFile(manifest.Path)
+			if !exists {
+				continue
+			}
+			dependencies, err := manifest.ParseDependencies(contents)
+			if err != nil {
+				// a manifest that can't be parsed has no dependencies rules can see
+				log.Warnf("%s of %s can't be parsed: %v\n", manifest.Path, *r.repo.Name, err)
+				continue
+			}
+			r.dependencies = append(r.dependencies, dependencies...)
+		}
+	}
+	return containsFold(r.dependencies, name)
+}
+
+// containsKeyword whether text has keyword as a whole word, ignoring case. e.g. "Docker" but not "dockerfile"
+func containsKeyword(text string, keyword string) bool {
+	text, keyword = strings.ToLower(text), strings.ToLower(strings.TrimSpace(keyword))
+	if keyword == "" {
+		return false
+	}
+	isWordRune := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], keyword)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(keyword)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if (start == 0 || !isWordRune(before)) && (end == len(text) || !isWordRune(after)) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func (rule TagRule) matches(r *tagRuleRepo) (bool, error) {
+	repo := r.repo
+
+	if rule.NameRegex != "" {
+		re, err := regexp.Compile(rule.NameRegex)
+		if err != nil {
+			return false, err
+		}
+		if !re.MatchString(repo.GetName()) {
+			return false, nil
+		}
+	}
+
+	if rule.Language != "" && !strings.EqualFold(rule.Language, repo.GetLanguage()) {
+		return false, nil
+	}
+
+	for _, topic := range rule.Topics {
+		if !containsFold(repo.Topics, topic) {
+			return false, nil
+		}
+	}
+
+	for _, keyword := range rule.ReadmeKeywords {
+		if !containsKeyword(r.readme, keyword) {
+			return false, nil
+		}
+	}
+
+	for _, filePath := range rule.Files {
+		if !r.hasFile(filePath) {
+			return false, nil
+		}
+	}
+
+	for _, dependency := range rule.Dependencies {
+		if !r.hasDependency(dependency) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func getTagRuleSource(i int, rule TagRule) string {
+	source := fmt.Sprintf("tags.rules[%d]", i)
+	if rule.Name != "" {
+		source += " (" + rule.Name + ")"
+	}
+	return source
+}
+
+// explainPostTags the tags of a repo's post in the order they were added, including the ones a rule removed.
+// tags come from tags.autoIfInRepoName, tags.repoNameMappings, tags.static and then tags.rules. tags.rename applies to all of them
+func explainPostTags(repo *github.Repository, readme string) ([]PostTagExplanation, error) {
+	config := getConfig()
+	explanations := make([]PostTagExplanation, 0)
+
+	rename := func(tag string) string {
+		if tagName, ok := config.Tags.Rename[tag]; ok {
+			return tagName
+		}
+		return tag
+	}
+	find := func(tag string) int {
+		for i := range explanations {
+			if explanations[i].Tag == tag {
+				return i
+			}
+		}
+		return -1
+	}
+	add := func(tag string, source string) {
+		tag = rename(tag)
+		if i := find(tag); i >= 0 {
+			explanations[i].RemovedBy = ""
+			explanations[i].Sources = append(explanations[i].Sources, source)
+			return
+		}
+		explanations = append(explanations, PostTagExplanation{Tag: tag, Sources: []string{source}})
+	}
+
+	words := strings.Split(*repo.Name, "-")
+	for _, tag := range unique(arrayIntersection(config.Tags.AutoIfInRepoName, words)) {
+		add(tag, "tags.autoIfInRepoName")
+	}
+	for _, tag := range config.Tags.RepoNameMappings[*repo.Name] {
+		add(tag, "tags.repoNameMappings")
+	}
+	for _, tag := range config.Tags.Static {
+		add(tag, "tags.static")
+	}
+
+	r := &tagRuleRepo{repo: repo, readme: readme, files: make(map[string]bool)}
+	for i, rule := range config.Tags.Rules {
+		match, err := rule.matches(r)
+		if err != nil {
+			return nil, fmt.Errorf("tags.rules[%d]: %v", i, err)
+		}
+		if !match {
+			continue
+		}
+		source := getTagRuleSource(i, rule)
+		for _, tag := range rule.Add {
+			add(tag, source)
+		}
+		for _, tag := range rule.Remove {
+			if j := find(rename(tag)); j >= 0 && explanations[j].RemovedBy == "" {
+				explanations[j].RemovedBy = source
+			}
+		}
+	}
+	return explanations, nil
+}
+
+func writePostTagsReport(w io.Writer, report PostTagsReport, format string) error {
+	if format == "json" {
+		b, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Printf("json.MarshalIndent failed\n")
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+
+	fmt.Fprintf(w, "%s\n", report.Repo)
+	for _, explanation := range report.Tags {
+		if explanation.RemovedBy != "" {
+			fmt.Fprintf(w, "  %s: added by %s, removed by %s\n", explanation.Tag, strings.Join(explanation.Sources, ", "), explanation.RemovedBy)
+		} else {
+			fmt.Fprintf(w, "  %s: %s\n", explanation.Tag, strings.Join(explanation.Sources, ", "))
+		}
+	}
+	return nil
+}
+
+// explainPostTagsForUser writes the tags of one repo's post and the rule or setting behind each one
+func explainPostTagsForUser(username string, repoName string, w io.Writer, format string, cache bool) error {
+	repos, err := getReposForUser(username, cache)
+	if err != nil {
+		log.Printf("getReposForUser(%s) failed\n", username)
+		return err
+	}
+	for _, repo := range repos {
+		if repo.GetName() != repoName {
+			continue
+		}
+		readme, err := getPostBodyForRepo(repo)
+		if err != nil {
+			log.Printf("getPostBodyForRepo(%s) failed\n", repoName)
+			return err
+		}
+		explanations, err := explainPostTags(repo, readme)
+		if err != nil {
+			log.Printf("explainPostTags(%s) failed\n", repoName)
+			return err
+		}
+		return writePostTagsReport(w, PostTagsReport{Repo: repoName, Tags: explanations}, format)
+	}
+	return fmt.Errorf("%s has no repo named %s", username, repoName)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/github"
+)
+
+const testTagRulesConfigYAML = `
+tags:
+  autoIfInRepoName: [aws, cdk]
+  static: [experiment]
+  rename:
+    js: javascript
+  rules:
+    - name: docker
+      files: [Dockerfile]
+      add: [docker]
+    - nameRegex: ^aws-cdk
+      add: [cdk, iac]
+    - dependencies: [aws-cdk-lib]
+      readmeKeywords: [typescript]
+      add: [js]
+    - files: [serverless.yml]
+      add: [serverless]
+    - readmeKeywords: [draft]
+      remove: [experiment]
+`
+
+func TestParseDependencyManifests(t *testing.T) {
+	for _, test := range []struct {
+		parse    func(string) ([]string, error)
+		contents string
+		want     []string
+	}{
+		{parsePackageJSONDependencies, `{"dependencies": {"aws-cdk-lib": "^2.0.0"}, "devDependencies": {"typescript": "~5.0"}}`, []string{"aws-cdk-lib", "typescript"}},
+		{parseGoModDependencies, "module example.com/m\n\nrequire github.com/spf13/cobra v1.8.0\n\nrequire (\n\tgolang.org/x/net v0.1.0 // indirect\n)\n", []string{"github.com/spf13/cobra", "golang.org/x/net"}},
+		{parseRequirementsDependencies, "# web\nFlask==3.0.0\nboto3[crt]>=1.28 ; python_version > '3.8'\n-r dev.txt\n", []string{"Flask", "boto3"}},
+		{parseCargoDependencies, "[package]\nname = \"m\"\n\n[dependencies]\ntokio = { version = \"1\" }\nserde = \"1\"\nanyhow = \"1\"\n\n[dev-dependencies]\nmockall = \"0.12\"\n", []string{"anyhow", "serde", "tokio", "mockall"}},
+	} {
+		dependencies, err := test.parse(test.contents)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(dependencies, test.want) {
+			t.Errorf("expected %v, got %v", test.want, dependencies)
+		}
+	}
+}
+
+func TestContainsKeyword(t *testing.T) {
+	for _, test := range []struct {
+		text, keyword string
+		want          bool
+	}{
+		{"Built with Docker.", "docker", true},
+		{"see the Dockerfile", "docker", false},
+		{"C++ and Rust", "c++", true},
+		{"terraform-aws", "terraform", true},
+		{"", "go", false},
+	} {
+		if got := containsKeyword(test.text, test.keyword); got != test.want {
+			t.Errorf("containsKeyword(%q, %q) = %v", test.text, test.keyword, got)
+		}
+	}
+}
+
+func TestExplainPostTags(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pfeilbr/aws-cdk-playground/main/Dockerfile":
+			w.Write([]byte("FROM node:20\n"))
+		case "/pfeilbr/aws-cdk-playground/main/package.json":
+			w.Write([]byte(`{"dependencies": {"aws-cdk-lib": "^2.0.0"}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	defer func(baseURL string, cache bool) {
+		rawContentBaseURL, useCache = baseURL, cache
+	}(rawContentBaseURL, useCache)
+	rawContentBaseURL, useCache = server.URL, false
+	withTestConfig(t, testTagRulesConfigYAML)
+
+	repo := newTestRepo("aws-cdk-playground")
+	repo.DefaultBranch = github.String("main")
+	explanations, err := explainPostTags(repo, "# AWS CDK\n\nA TypeScript draft.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PostTagExplanation{
+		{Tag: "aws", Sources: []string{"tags.autoIfInRepoName"}},
+		{Tag: "cdk", Sources: []string{"tags.autoIfInRepoName", "tags.rules[1]"}},
+		{Tag: "experiment", Sources: []string{"tags.static"}, RemovedBy: "tags.rules[4]"},
+		{Tag: "docker", Sources: []string{"tags.rules[0] (docker)"}},
+		{Tag: "iac", Sources: []string{"tags.rules[1]"}},
+		{Tag: "javascript", Sources: []string{"tags.rules[2]"}},
+	}
+	if !reflect.DeepEqual(explanations, want) {
+		t.Errorf("expected %+v, got %+v", want, explanations)
+	}
+
+	tags, err := getPostTags(repo, "# AWS CDK\n\nA TypeScript draft.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tags, []string{"aws", "cdk", "docker", "iac", "javascript"}) {
+		t.Errorf("unexpected tags %v", tags)
+	}
+
+	var buf bytes.Buffer
+	if err := writePostTagsReport(&buf, PostTagsReport{Repo: repo.GetName(), Tags: explanations[1:3]}, "text"); err != nil {
+		t.Fatal(err)
+	}
+	wantReport := "aws-cdk-playground\n  cdk: tags.autoIfInRepoName, tags.rules[1]\n  experiment: added by tags.static, removed by tags.rules[4]\n"
+	if buf.String() != wantReport {
+		t.Errorf("expected %q, got %q", wantReport, buf.String())
+	}
+}
+
+func TestExplainPostTagsFetchFailures(t *testing.T) {
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		switch r.URL.Path {
+		case "/pfeilbr/lambda-playground/master/Dockerfile":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	defer func(baseURL string, cache bool) {
+		rawContentBaseURL, useCache = baseURL, cache
+	}(rawContentBaseURL, useCache)
+	rawContentBaseURL, useCache = server.URL, false
+	missingRepoFiles = make(map[string]bool)
+	withTestConfig(t, testTagRulesConfigYAML)
+
+	repo := newTestRepo("lambda-playground")
+	for i := 0; i < 2; i++ {
+		tags, err := getPostTags(repo, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tags, []string{"experiment"}) {
+			t.Errorf("expected the rules needing files to be skipped, got %v", tags)
+		}
+	}
+	if requests["/pfeilbr/lambda-playground/master/serverless.yml"] != 1 {
+		t.Errorf("expected the 404 to be remembered, got %v", requests)
+	}
+	if requests["/pfeilbr/lambda-playground/master/Dockerfile"] != 2 {
+		t.Errorf("expected the 503 to be tried again, got %v", requests)
+	}
+
+	// the next run looks again in case the repo added the file
+	missingRepoFiles = make(map[string]bool)
+	if _, err := getPostTags(repo, ""); err != nil {
+		t.Fatal(err)
+	}
+	if requests["/pfeilbr/lambda-playground/master/serverless.yml"] != 2 {
+		t.Errorf("expected the 404 to only be remembered for the run, got %v", requests)
+	}
+}
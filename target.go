@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/github"
+	log "github.com/sirupsen/logrus"
+)
+
+const hugoTarget = "hugo"
+const jekyllTarget = "jekyll"
+
+// OutputTarget site generator posts are written for
+type OutputTarget struct {
+	Name               string
+	TemplateName       string
+	FrontMatterFormat  string
+	FrontMatterFormats []string
+	FrontMatter        func(repoPost *RepoPost) FrontMatter
+	PostFileName       func(repoPost *RepoPost) string
+	TransformBody      func(repo *github.Repository, markdown string) string
+	RenderPost         func(repoPost *RepoPost) (string, error)
+	WriteSite          func(repoPosts []RepoPost, destinationDirectory string) error
+	// PlainHTMLShortcodes GFM constructs become plain HTML whatever body.gfmShortcodes says, the site has no hugo shortcodes
+	PlainHTMLShortcodes bool
+	// PostURLPattern path a post is served from on the generated site. see getPostURLPath
+	PostURLPattern string
+}
+
+var outputTargets = map[string]OutputTarget{
+	hugoTarget: {
+		Name:               hugoTarget,
+		TemplateName:       postTemplateName,
+		FrontMatterFormat:  tomlFrontMatterFormat,
+		FrontMatterFormats: []string{tomlFrontMatterFormat, yamlFrontMatterFormat, jsonFrontMatterFormat},
+		FrontMatter:        getHugoFrontMatter,
+		PostFileName: func(repoPost *RepoPost) string {
+			return getPostFileNameForRepo(repoPost.Repo)
+		},
+		PostURLPattern: "/post/{slug}/",
+	},
+	jekyllTarget: {
+		Name:               jekyllTarget,
+		TemplateName:       postTemplateName,
+		FrontMatterFormat:  yamlFrontMatterFormat,
+		FrontMatterFormats: []string{yamlFrontMatterFormat},
+		FrontMatter:        getJekyllFrontMatter,
+		PostFileName:       getJekyllPostFileName,
+		TransformBody:      rewriteRelativeImagePaths,
+		PostURLPattern:     "/{slug}/",
+	},
+	htmlTarget: {
+		Name:                htmlTarget,
+		TemplateName:        postTemplateName,
+		PostFileName:        getHTMLPostFileName,
+		TransformBody:       rewriteRelativeImagePaths,
+		RenderPost:          renderHTMLPost,
+		WriteSite:           createHTMLSite,
+		PostURLPattern:      "/posts/{slug}.html",
+		PlainHTMLShortcodes: true,
+	},
+}
+
+var markdownImageTargetRegexp = regexp.MustCompile(`(!\[[^\]]*\]\()([^)\s]+)`)
+var htmlImageSourceRegexp = regexp.MustCompile(`(<img[^>]*\ssrc=["'])([^"']+)`)
+
+func getOutputTarget() (OutputTarget, error) {
+	name := target
+	if name == "" {
+		name = hugoTarget
+	}
+	outputTarget, ok := outputTargets[name]
+	if !ok {
+		return OutputTarget{}, fmt.Errorf("unknown target %q", name)
+	}
+	return outputTarget, nil
+}
+
+// getPostURLPath the site relative URL of a post from outputs.postURLPattern or the target's pattern.
+// {slug}, {name}, {year}, {month} and {day} are replaced. e.g. /post/{slug}/ -> /post/lambda-playground/
+func getPostURLPath(repoPost *RepoPost, outputTarget OutputTarget) string {
+	pattern := getConfig().Outputs.PostURLPattern
+	if pattern == "" {
+		pattern = outputTarget.PostURLPattern
+	}
+	date, _ := getPostDate(repoPost.Repo)
+	return strings.NewReplacer(
+		"{slug}", repoPost.Slug,
+		"{name}", repoPost.Repo.GetName(),
+		"{year}", date.Format("2006"),
+		"{month}", date.Format("01"),
+		"{day}", date.Format("02"),
+	).Replace(pattern)
+}
+
+func getHugoFrontMatter(repoPost *RepoPost) FrontMatter {
+	frontMatter := newFrontMatter(repoPost)
+	frontMatter.Summary = " "
+	frontMatter.Truncated = true
+	frontMatter.Aliases = repoPost.Aliases
+	return frontMatter
+}
+
+func getJekyllFrontMatter(repoPost *RepoPost) FrontMatter {
+	frontMatter := newFrontMatter(repoPost)
+	frontMatter.Layout = "post"
+	frontMatter.Permalink = "/" + repoPost.Slug + "/"
+	return frontMatter
+}
+
+// getJekyllPostFileName _posts/YYYY-MM-DD-<slug>.md named after the date of the post, the same date as its front matter
+func getJekyllPostFileName(repoPost *RepoPost) string {
+	date, ok := getPostDate(repoPost.Repo)
+	if !ok {
+		log.Warnf("no created, pushed or updated date for %s, naming its post after %s\n", repoPost.Repo.GetName(), date.Format(postDateFormat))
+	}
+	return filepath.Join("_posts", date.Format(postDateFormat)+"-"+repoPost.Slug+".md")
+}
+
+func isRelativeURL(target string) bool {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	return !u.IsAbs() && u.Host == "" && !strings.HasPrefix(target, "#")
+}
+
+// rewriteRelativeImagePaths points images referenced relative to the README at the raw file in the repo.
+// posts are served from a permalink so paths relative to the repo root don't resolve.
+func rewriteRelativeImagePaths(repo *github.Repository, markdown string) string {
+	rewrite := func(re *regexp.Regexp) func(string) string {
+		return func(match string) string {
+			parts := re.FindStringSubmatch(match)
+			if !isRelativeURL(parts[2]) {
+				return match
+			}
+			return parts[1] + getRawFileURL(repo, strings.TrimPrefix(parts[2], "./"))
+		}
+	}
+
+	return mapMarkdownText(markdown, func(text string) string {
+		return mapMarkdownInlineText(text, func(text string) string {
+			text = markdownImageTargetRegexp.ReplaceAllStringFunc(text, rewrite(markdownImageTargetRegexp))
+			return htmlImageSourceRegexp.ReplaceAllStringFunc(text, rewrite(htmlImageSourceRegexp))
+		})
+	})
+}
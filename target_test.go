@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+func TestGetJekyllPostFileNameWithoutCreatedAt(t *testing.T) {
+	repo := newTestRepo("jekyll-playground")
+	check := func(want string, message string) {
+		t.Helper()
+		repoPost := &RepoPost{Repo: repo, Slug: "jekyll"}
+		if result := getJekyllPostFileName(repoPost); result != "_posts/"+want+"-jekyll.md" {
+			t.Errorf("expected %s, got %s", message, result)
+		}
+		// jekyll uses the front matter date over the one in the file name
+		if date := getJekyllFrontMatter(repoPost).Date.Format(postDateFormat); date != want {
+			t.Errorf("expected the front matter date %s, got %s", want, date)
+		}
+	}
+
+	repo.PushedAt = &github.Timestamp{Time: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	check("2020-01-02", "the pushed date")
+
+	repo.PushedAt = nil
+	repo.UpdatedAt = &github.Timestamp{Time: time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)}
+	check("2021-03-04", "the updated date")
+
+	repo.UpdatedAt = nil
+	check("1970-01-01", "the same name on every run")
+}
+
+func TestJekyllTarget(t *testing.T) {
+	defer func(name string) { target = name }(target)
+	target = jekyllTarget
+
+	repo := newTestRepo("jekyll-playground")
+	language := "Go"
+	repo.Language = &language
+	repo.CreatedAt = &github.Timestamp{Time: time.Date(2019, 9, 10, 21, 55, 7, 0, time.UTC)}
+	repoPost := &RepoPost{Repo: repo, Title: "Jekyll", Slug: "jekyll", Tags: []string{"go", "c++"}, Author: Author{Name: "Brian Pfeil"}}
+
+	outputTarget, err := getOutputTarget()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result := outputTarget.PostFileName(repoPost); result != "_posts/2019-09-10-jekyll.md" {
+		t.Errorf("got %s, want %s", result, "_posts/2019-09-10-jekyll.md")
+	}
+
+	result, err := getPostFileContents(repoPost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"---\nauthor: Brian Pfeil\nlayout: post\n", "date: 2019-09-10\n", "tags:\n  - go\n  - c++\n", "permalink: /jekyll/\n"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in %q", want, result)
+		}
+	}
+}
+
+func TestRewriteRelativeImagePaths(t *testing.T) {
+	repo := newTestRepo("jekyll-playground")
+	markdown := "![arch](./images/arch.png) ![abs](https://example.com/a.png) <img src=\"docs/b.png\" width=\"10\">\n\n```md\n![code](c.png)\n```\n"
+	want := "![arch](https://raw.githubusercontent.com/pfeilbr/jekyll-playground/master/images/arch.png) ![abs](https://example.com/a.png) " +
+		"<img src=\"https://raw.githubusercontent.com/pfeilbr/jekyll-playground/master/docs/b.png\" width=\"10\">\n\n```md\n![code](c.png)\n```\n"
+	if result := rewriteRelativeImagePaths(repo, markdown); result != want {
+		t.Errorf("got %q, want %q", result, want)
+	}
+}
+
+func TestUnknownTarget(t *testing.T) {
+	defer func(name string) { target = name }(target)
+	target = "gatsby"
+	if _, err := getOutputTarget(); err == nil {
+		t.Errorf("expected error for unknown target")
+	}
+}
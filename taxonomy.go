@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	log "github.com/sirupsen/logrus"
+)
+
+// Taxonomy a kind of term posts are grouped by, e.g. tags
+type Taxonomy struct {
+	Name         string
+	Terms        func(repoPost *RepoPost) []string
+	Descriptions func(config *Config) map[string]string
+}
+
+// TaxonomyTerm a term and the posts it is applied to
+type TaxonomyTerm struct {
+	Name        string         `json:"name"`
+	Slug        string         `json:"slug"`
+	Count       int            `json:"count"`
+	Description string         `json:"description,omitempty"`
+	Posts       []TaxonomyPost `json:"posts"`
+}
+
+// TaxonomyPost a post listed under a term
+type TaxonomyPost struct {
+	Title        string `json:"title"`
+	Slug         string `json:"slug"`
+	RepoFullName string `json:"repoFullName"`
+}
+
+// TaxonomyIndexFrontMatter front matter of a content/<taxonomy>/<term>/_index.md landing page
+type TaxonomyIndexFrontMatter struct {
+	Title       string `toml:"title"`
+	Description string `toml:"description,omitempty"`
+}
+
+var taxonomies = []Taxonomy{
+	{
+		Name:         "tags",
+		Terms:        func(repoPost *RepoPost) []string { return repoPost.Tags },
+		Descriptions: func(config *Config) map[string]string { return config.Outputs.Taxonomy.TagDescriptions },
+	},
+	{
+		Name:         "categories",
+		Terms:        getPostCategories,
+		Descriptions: func(config *Config) map[string]string { return config.Outputs.Taxonomy.CategoryDescriptions },
+	},
+}
+
+// groupRepoPostsByTerm posts for each term in order of term name
+func groupRepoPostsByTerm(repoPosts []RepoPost, terms func(repoPost *RepoPost) []string) ([]string, map[string][]RepoPost) {
+	postsByTerm := make(map[string][]RepoPost)
+	for i := range repoPosts {
+		for _, term := range unique(terms(&repoPosts[i])) {
+			postsByTerm[term] = append(postsByTerm[term], repoPosts[i])
+		}
+	}
+
+	names := make([]string, 0, len(postsByTerm))
+	for name := range postsByTerm {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, postsByTerm
+}
+
+// getTaxonomyTermSlug the directory hugo uses for a term. e.g. "Machine Learning" -> machine-learning
+func getTaxonomyTermSlug(term string) string {
+	return strings.Join(strings.Fields(strings.ToLower(term)), "-")
+}
+
+func getTaxonomyTerms(repoPosts []RepoPost, taxonomy Taxonomy, descriptions map[string]string) []TaxonomyTerm {
+	names, postsByTerm := groupRepoPostsByTerm(repoPosts, taxonomy.Terms)
+
+	terms := make([]TaxonomyTerm, 0, len(names))
+	for _, name := range names {
+		term := TaxonomyTerm{
+			Name:        name,
+			Slug:        getTaxonomyTermSlug(name),
+			Count:       len(postsByTerm[name]),
+			Description: descriptions[name],
+			Posts:       make([]TaxonomyPost, 0),
+		}
+		for _, repoPost := range postsByTerm[name] {
+			term.Posts = append(term.Posts, TaxonomyPost{
+				Title:        repoPost.Title,
+				Slug:         repoPost.Slug,
+				RepoFullName: repoPost.Repo.GetFullName(),
+			})
+		}
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+func getTaxonomyIndexPage(term TaxonomyTerm) (string, error) {
+	var buf bytes.Buffer
+	buf.WriteString("+++\n")
+	if err := toml.NewEncoder(&buf).Encode(TaxonomyIndexFrontMatter{Title: term.Name, Description: term.Description}); err != nil {
+		return "", err
+	}
+	buf.WriteString("+++\n")
+	if term.Description != "" {
+		buf.WriteString("\n" + term.Description + "\n")
+	}
+	return buf.String(), nil
+}
+
+// createTaxonomyFiles writes data/<taxonomy>.json with the count and posts of every term and,
+// when indexPages is set, a content/<taxonomy>/<term>/_index.md landing page for every term with a description
+func createTaxonomyFiles(repoPosts []RepoPost, siteDirectory string, indexPages bool) error {
+	for _, taxonomy := range taxonomies {
+		descriptions := taxonomy.Descriptions(getConfig())
+		terms := getTaxonomyTerms(repoPosts, taxonomy, descriptions)
+
+		b, err := json.MarshalIndent(terms, "", "  ")
+		if err != nil {
+			log.Printf("json.MarshalIndent(%s) failed\n", taxonomy.Name)
+			return err
+		}
+		if err := writeSiteFile(siteDirectory, filepath.Join("data", taxonomy.Name+".json"), b); err != nil {
+			return err
+		}
+
+		if !indexPages {
+			continue
+		}
+		for _, term := range terms {
+			if term.Description == "" {
+				continue
+			}
+			page, err := getTaxonomyIndexPage(term)
+			if err != nil {
+				log.Printf("getTaxonomyIndexPage(%s) failed\n", term.Name)
+				return err
+			}
+			if err := writeSiteFile(siteDirectory, filepath.Join("content", taxonomy.Name, term.Slug, "_index.md"), []byte(page)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func createTaxonomyFilesForUser(username string, siteDirectory string) error {
+	repoPosts, err := getRepoPosts(username)
+	if err != nil {
+		log.Printf("getRepoPosts(%s) failed\n", username)
+		return err
+	}
+	return createTaxonomyFiles(repoPosts, siteDirectory, getConfig().Outputs.Taxonomy.IndexPages)
+}
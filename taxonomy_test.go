@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCreateTaxonomyFiles(t *testing.T) {
+	siteDirectory, err := ioutil.TempDir("", "site")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(siteDirectory)
+
+	os.Setenv("TAG_DESCRIPTIONS_JSON", `{"aws": "Amazon Web Services experiments"}`)
+	defer os.Unsetenv("TAG_DESCRIPTIONS_JSON")
+
+	language := "Go"
+	lambda := newTestRepo("lambda-playground")
+	lambda.Language = &language
+	repoPosts := []RepoPost{
+		{Repo: lambda, Title: "Lambda", Slug: "lambda-playground", Tags: []string{"aws", "serverless"}},
+		{Repo: newTestRepo("s3-playground"), Title: "S3", Slug: "s3-playground", Tags: []string{"aws", "aws"}},
+	}
+
+	if err := createTaxonomyFiles(repoPosts, siteDirectory, true); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(siteDirectory, "data", "tags.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tags []TaxonomyTerm
+	if err := json.Unmarshal(b, &tags); err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 2 || tags[0].Name != "aws" || tags[0].Count != 2 || tags[0].Description == "" || tags[1].Count != 1 {
+		t.Errorf("unexpected tags %+v", tags)
+	}
+	if tags[0].Posts[0].RepoFullName != "pfeilbr/lambda-playground" {
+		t.Errorf("unexpected tag posts %+v", tags[0].Posts)
+	}
+
+	b, err = ioutil.ReadFile(filepath.Join(siteDirectory, "data", "categories.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var categories []TaxonomyTerm
+	if err := json.Unmarshal(b, &categories); err != nil {
+		t.Fatal(err)
+	}
+	if len(categories) != 2 || categories[0].Name != "Go" || categories[1].Name != "playground" || categories[1].Count != 2 {
+		t.Errorf("unexpected categories %+v", categories)
+	}
+
+	b, err = ioutil.ReadFile(filepath.Join(siteDirectory, "content", "tags", "aws", "_index.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `description = "Amazon Web Services experiments"`) {
+		t.Errorf("unexpected index page %s", b)
+	}
+	if _, err := os.Stat(filepath.Join(siteDirectory, "content", "tags", "serverless")); !os.IsNotExist(err) {
+		t.Errorf("expected no index page for a tag without a description")
+	}
+}
+
+func TestGetTaxonomyTermSlug(t *testing.T) {
+	if slug := getTaxonomyTermSlug("Machine  Learning"); slug != "machine-learning" {
+		t.Errorf("got %q", slug)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const readmeFeatureCode = "code"
+const readmeFeatureImages = "images"
+const readmeFeatureMermaid = "mermaid"
+const readmeFeatureHeadings = "headings"
+const readmeFeatureTables = "tables"
+
+// TemplateRule selects a template for posts whose repo matches every condition set on the rule
+type TemplateRule struct {
+	Template       string   `json:"template" yaml:"template"`
+	NameRegex      string   `json:"nameRegex,omitempty" yaml:"nameRegex,omitempty"`
+	Language       string   `json:"language,omitempty" yaml:"language,omitempty"`
+	Topics         []string `json:"topics,omitempty" yaml:"topics,omitempty"`
+	ReadmeRegex    string   `json:"readmeRegex,omitempty" yaml:"readmeRegex,omitempty"`
+	ReadmeFeatures []string `json:"readmeFeatures,omitempty" yaml:"readmeFeatures,omitempty"`
+}
+
+var markdownTableRegexp = regexp.MustCompile(`(?m)^\s*\|?\s*:?-{3,}:?\s*\|`)
+var anyImageRegexp = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)|<img\s`)
+
+func getTemplateRules() ([]TemplateRule, error) {
+	rules := getConfig().Templates.Rules
+	for i, rule := range rules {
+		if rule.Template == "" {
+			return nil, fmt.Errorf("templates.rules[%d] is missing a template", i)
+		}
+	}
+	return rules, nil
+}
+
+// getReadmeFeatures the notable kinds of content in a README
+func getReadmeFeatures(markdown string) map[string]bool {
+	features := make(map[string]bool)
+	for _, segment := range splitMarkdownFences(markdown) {
+		if segment.Fenced {
+			features[readmeFeatureCode] = true
+			if getFenceLanguage(segment.Info) == "mermaid" {
+				features[readmeFeatureMermaid] = true
+			}
+			continue
+		}
+		if anyImageRegexp.MatchString(segment.Text) {
+			features[readmeFeatureImages] = true
+		}
+		if markdownTableRegexp.MatchString(segment.Text) {
+			features[readmeFeatureTables] = true
+		}
+		for _, line := range strings.Split(segment.Text, "\n") {
+			if headingRegexp.MatchString(line) {
+				features[readmeFeatureHeadings] = true
+				break
+			}
+		}
+	}
+	return features
+}
+
+func containsFold(items []string, value string) bool {
+	for _, item := range items {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rule TemplateRule) matches(repoPost *RepoPost, readme string, features map[string]bool) (bool, error) {
+	repo := repoPost.Repo
+
+	if rule.NameRegex != "" {
+		re, err := regexp.Compile(rule.NameRegex)
+		if err != nil {
+			return false, err
+		}
+		if !re.MatchString(repo.GetName()) {
+			return false, nil
+		}
+	}
+
+	if rule.Language != "" && !strings.EqualFold(rule.Language, repo.GetLanguage()) {
+		return false, nil
+	}
+
+	for _, topic := range rule.Topics {
+		if !containsFold(repo.Topics, topic) {
+			return false, nil
+		}
+	}
+
+	if rule.ReadmeRegex != "" {
+		re, err := regexp.Compile(rule.ReadmeRegex)
+		if err != nil {
+			return false, err
+		}
+		if !re.MatchString(readme) {
+			return false, nil
+		}
+	}
+
+	for _, feature := range rule.ReadmeFeatures {
+		if !features[feature] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// selectTemplate the template of the first rule the post matches, otherwise fallback.
+// readme is the README as fetched, before the body is cleaned up and transformed for the target
+func selectTemplate(repoPost *RepoPost, readme string, rules []TemplateRule, fallback string) (string, error) {
+	features := getReadmeFeatures(readme)
+	for i, rule := range rules {
+		match, err := rule.matches(repoPost, readme, features)
+		if err != nil {
+			return "", fmt.Errorf("template rule %d: %v", i, err)
+		}
+		if match {
+			return rule.Template, nil
+		}
+	}
+	return fallback, nil
+}
+
+func getDefaultTemplateName(outputTarget OutputTarget) string {
+	if name := getConfig().Templates.Default; name != "" {
+		return name
+	}
+	return outputTarget.TemplateName
+}
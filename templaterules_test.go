@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestSelectTemplate(t *testing.T) {
+	rules := []TemplateRule{
+		{Template: "library.md", NameRegex: "^go-", Language: "go"},
+		{Template: "tutorial.md", Topics: []string{"tutorial"}},
+		{Template: "diagram.md", ReadmeFeatures: []string{readmeFeatureMermaid, readmeFeatureHeadings}},
+		{Template: "experiment.md", ReadmeRegex: "(?i)experiment"},
+	}
+
+	language := "Go"
+	goRepo := newTestRepo("go-cache")
+	goRepo.Language = &language
+	tutorialRepo := newTestRepo("aws-cdk-playground")
+	tutorialRepo.Topics = []string{"aws", "Tutorial"}
+
+	tests := []struct {
+		name     string
+		repoPost *RepoPost
+		readme   string
+		want     string
+	}{
+		{name: "name and language", repoPost: &RepoPost{Repo: goRepo}, want: "library.md"},
+		{name: "topics", repoPost: &RepoPost{Repo: tutorialRepo}, want: "tutorial.md"},
+		{name: "readme features", repoPost: &RepoPost{Repo: newTestRepo("a")}, readme: "## Flow\n\n```mermaid\nA-->B\n```\n", want: "diagram.md"},
+		{name: "readme regex", repoPost: &RepoPost{Repo: newTestRepo("b")}, readme: "an Experiment", want: "experiment.md"},
+		{name: "fallback", repoPost: &RepoPost{Repo: newTestRepo("c")}, readme: "```mermaid\nA-->B\n```\n", want: postTemplateName},
+		{name: "readme before transforms", repoPost: &RepoPost{Repo: newTestRepo("e"), MarkdownBody: "## Flow\n\n{{< mermaid >}}\nA-->B\n{{< /mermaid >}}\n"}, readme: "## Flow\n\n```mermaid\nA-->B\n```\n", want: "diagram.md"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := selectTemplate(test.repoPost, test.readme, rules, postTemplateName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != test.want {
+				t.Errorf("got %s, want %s", result, test.want)
+			}
+		})
+	}
+
+	if _, err := selectTemplate(&RepoPost{Repo: newTestRepo("d")}, "", []TemplateRule{{Template: "x.md", NameRegex: "("}}, postTemplateName); err == nil {
+		t.Errorf("expected error for invalid regex")
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+	"github.com/google/go-github/github"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"gopkg.in/yaml.v3"
+)
+
+const postTemplateName = "post.md"
+
+// defaultTemplates are used for any template -template-dir doesn't override
+//
+//go:embed templates
+var defaultTemplates embed.FS
+
+var slugInvalidCharactersRegexp = regexp.MustCompile(`[^a-z0-9]+`)
+
+var templateFuncMap = template.FuncMap{
+	"slugify":     slugify,
+	"toml":        toTOML,
+	"yaml":        toYAML,
+	"join":        join,
+	"truncate":    truncate,
+	"markdownify": markdownify,
+	"dateFormat":  dateFormat,
+	"default":     defaultValue,
+}
+
+func slugify(s string) string {
+	return strings.Trim(slugInvalidCharactersRegexp.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+// toTOML a value as a TOML value. e.g. {{ .Title | toml }} -> "My \"quoted\" title"
+func toTOML(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(map[string]interface{}{"v": v}); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimPrefix(buf.String(), "v = ")), nil
+}
+
+func toYAML(v interface{}) (string, error) {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(b), "\n"), nil
+}
+
+// join e.g. {{ .Tags | join ", " }}
+func join(separator string, items []string) string {
+	return strings.Join(items, separator)
+}
+
+// truncate shortens s to at most length runes ending with an ellipsis. e.g. {{ .Summary | truncate 100 }}
+func truncate(length int, s string) string {
+	if utf8.RuneCountInString(s) <= length {
+		return s
+	}
+	runes := []rune(s)
+	if length < 1 {
+		return ""
+	}
+	return strings.TrimSpace(string(runes[:length-1])) + "…"
+}
+
+func markdownify(markdown string) (string, error) {
+	var buf bytes.Buffer
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+	if err := md.Convert([]byte(markdown), &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// dateFormat e.g. {{ .Repo.CreatedAt | dateFormat "January 2, 2006" }}
+func dateFormat(layout string, date interface{}) (string, error) {
+	switch t := date.(type) {
+	case time.Time:
+		return t.Format(layout), nil
+	case *time.Time:
+		return t.Format(layout), nil
+	case github.Timestamp:
+		return t.Format(layout), nil
+	case *github.Timestamp:
+		if t == nil {
+			return "", nil
+		}
+		return t.Format(layout), nil
+	}
+	return "", fmt.Errorf("dateFormat: unsupported type %T", date)
+}
+
+// defaultValue returns value unless it is empty. e.g. {{ .Repo.Description | default "no description" }}
+func defaultValue(fallback interface{}, value interface{}) interface{} {
+	if value == nil {
+		return fallback
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return fallback
+		}
+		return defaultValue(fallback, v.Elem().Interface())
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		if v.Len() == 0 {
+			return fallback
+		}
+	case reflect.Bool:
+		if !v.Bool() {
+			return fallback
+		}
+	}
+	return value
+}
+
+func parseTemplateFiles(t *template.Template, fsys fs.FS, patterns ...string) (*template.Template, error) {
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		if t, err = t.ParseFS(fsys, pattern); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// loadTemplates parses the embedded default templates then any templates in templateDirectory.
+// every file is a template named after its file name so templates in templateDirectory replace the defaults
+// and files in a partials directory can be used with {{ template "name.md" . }}
+func loadTemplates(templateDirectory string) (*template.Template, error) {
+	templates, err := fs.Sub(defaultTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := parseTemplateFiles(template.New("").Funcs(templateFuncMap), templates, "*.md", "partials/*")
+	if err != nil {
+		return nil, err
+	}
+
+	if templateDirectory != "" {
+		t, err = parseTemplateFiles(t, os.DirFS(templateDirectory), "*.md", "partials/*")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{template: `{{ "Hello, World!" | slugify }}`, want: "hello-world"},
+		{template: `{{ .Title | toml }}`, want: `"Say \"hi\""`},
+		{template: `{{ .Tags | yaml }}`, want: "- go\n- c++"},
+		{template: `{{ .Tags | join ", " }}`, want: "go, c++"},
+		{template: `{{ "abcdefghij" | truncate 5 }}`, want: "abcd…"},
+		{template: `{{ "**hi**" | markdownify }}`, want: "<p><strong>hi</strong></p>\n"},
+		{template: `{{ .Repo.CreatedAt | dateFormat "2006-01-02" }}`, want: "2019-09-10"},
+		{template: `{{ .Summary | default "none" }} {{ .Title | default "none" }}`, want: `none Say "hi"`},
+	}
+
+	repo := newTestRepo("template-playground")
+	repo.CreatedAt = &github.Timestamp{Time: time.Date(2019, 9, 10, 0, 0, 0, 0, time.UTC)}
+	repoPost := &RepoPost{Repo: repo, Title: `Say "hi"`, Tags: []string{"go", "c++"}}
+
+	for _, test := range tests {
+		t.Run(test.template, func(t *testing.T) {
+			tmpl, err := loadTemplates("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tmpl, err = tmpl.New("test").Parse(test.template); err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, repoPost); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != test.want {
+				t.Errorf("got %q, want %q", buf.String(), test.want)
+			}
+		})
+	}
+}
+
+func TestTemplateDirectory(t *testing.T) {
+	templateDirectory, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(templateDirectory)
+
+	os.MkdirAll(filepath.Join(templateDirectory, "partials"), 0755)
+	ioutil.WriteFile(filepath.Join(templateDirectory, "post.md"), []byte(`{{ template "footer.md" . }}`), 0644)
+	ioutil.WriteFile(filepath.Join(templateDirectory, "partials", "footer.md"), []byte(`source: {{ .Repo.FullName }}`), 0644)
+
+	tmpl, err := loadTemplates(templateDirectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, postTemplateName, &RepoPost{Repo: newTestRepo("template-playground")}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "source: pfeilbr/template-playground" {
+		t.Errorf("got %q, want %q", buf.String(), "source: pfeilbr/template-playground")
+	}
+
+	tmpl, _ = loadTemplates("")
+	buf.Reset()
+	tmpl.ExecuteTemplate(&buf, postTemplateName, &RepoPost{Repo: newTestRepo("template-playground"), MarkdownBody: "body"})
+	if !strings.Contains(buf.String(), "code for article") {
+		t.Errorf("expected embedded default template. got %q", buf.String())
+	}
+}
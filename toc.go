@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+const defaultReadingWordsPerMinute = 200
+
+const tocModeInject = "inject"
+const tocModeFrontMatter = "front-matter"
+
+// TOCEntry a heading in a post body
+type TOCEntry struct {
+	Level  int    `toml:"level" yaml:"level" json:"level"`
+	Title  string `toml:"title" yaml:"title" json:"title"`
+	Anchor string `toml:"anchor" yaml:"anchor" json:"anchor"`
+}
+
+var headingRegexp = regexp.MustCompile(`^ {0,3}(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+var markdownImageRegexp = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+var markdownLinkTextRegexp = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+var htmlTagRegexp = regexp.MustCompile(`<[^>]+>`)
+var anchorInvalidCharactersRegexp = regexp.MustCompile(`[^\p{L}\p{N}\s_-]`)
+var markdownSpecialCharactersRegexp = regexp.MustCompile("([\\\\`*_\\[\\]<])")
+var shortcodeTagRegexp = regexp.MustCompile(`\{\{[<%].*?[%>]\}\}`)
+
+// stripInlineMarkdown reduces inline markdown to its plain text
+func stripInlineMarkdown(text string) string {
+	text = markdownImageRegexp.ReplaceAllString(text, "$1")
+	text = markdownLinkTextRegexp.ReplaceAllString(text, "$1")
+	text = htmlTagRegexp.ReplaceAllString(text, "")
+	text = strings.NewReplacer("`", "", "**", "", "__", "", "*", "").Replace(text)
+	return strings.TrimSpace(text)
+}
+
+// getHeadingAnchor returns the github and hugo style anchor for a heading title
+func getHeadingAnchor(title string) string {
+	anchor := strings.ToLower(title)
+	anchor = anchorInvalidCharactersRegexp.ReplaceAllString(anchor, "")
+	return strings.Join(strings.Fields(anchor), "-")
+}
+
+// getHeadingOutline returns every ATX and setext heading in the markdown as a CommonMark parser finds them, so headings in code are left out.
+// shortcodes are dropped from the titles
+func getHeadingOutline(markdown string) []TOCEntry {
+	entries := make([]TOCEntry, 0)
+	anchorCounts := make(map[string]int)
+
+	source := []byte(markdown)
+	document := goldmark.New(goldmark.WithExtensions(extension.GFM)).Parser().Parse(text.NewReader(source))
+	ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := node.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		title := strings.Join(strings.Fields(shortcodeTagRegexp.ReplaceAllString(string(heading.Text(source)), "")), " ")
+		anchor := getHeadingAnchor(title)
+		if count := anchorCounts[anchor]; count > 0 {
+			anchorCounts[anchor]++
+			anchor = anchor + "-" + strconv.Itoa(count)
+		} else {
+			anchorCounts[anchor] = 1
+		}
+		entries = append(entries, TOCEntry{Level: heading.Level, Title: title, Anchor: anchor})
+		return ast.WalkSkipChildren, nil
+	})
+	return entries
+}
+
+// escapeMarkdownText escapes the characters that would make text markdown syntax
+func escapeMarkdownText(text string) string {
+	return markdownSpecialCharactersRegexp.ReplaceAllString(text, `\$1`)
+}
+
+// getTableOfContentsMarkdown renders the outline as a nested markdown list
+func getTableOfContentsMarkdown(entries []TOCEntry) string {
+	if len(entries) == 0 {
+		return ""
+	}
+
+	minLevel := entries[0].Level
+	for _, entry := range entries {
+		if entry.Level < minLevel {
+			minLevel = entry.Level
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("**Contents**\n\n")
+	for _, entry := range entries {
+		b.WriteString(fmt.Sprintf("%s- [%s](#%s)\n", strings.Repeat("  ", entry.Level-minLevel), escapeMarkdownText(entry.Title), entry.Anchor))
+	}
+	return b.String()
+}
+
+// stripShortcodes removes shortcode tags from markdown along with the contents of the paired codeShortcodes, like a mermaid diagram
+func stripShortcodes(markdown string, codeShortcodes ...string) string {
+	for _, name := range codeShortcodes {
+		if name == noneShortcode || name == htmlShortcode || name == "" {
+			continue
+		}
+		quoted := regexp.QuoteMeta(name)
+		paired := regexp.MustCompile(`(?s)\{\{[<%]\s*` + quoted + `\b.*?[%>]\}\}.*?\{\{[<%]\s*/` + quoted + `\s*[%>]\}\}`)
+		markdown = paired.ReplaceAllString(markdown, "")
+	}
+	return shortcodeTagRegexp.ReplaceAllString(markdown, "")
+}
+
+// countWords counts the words in markdown prose. code blocks, code spans, HTML blocks, shortcode tags and the contents of codeShortcodes are not counted
+func countWords(markdown string, codeShortcodes ...string) int {
+	var prose strings.Builder
+	source := []byte(stripShortcodes(markdown, codeShortcodes...))
+	document := goldmark.New(goldmark.WithExtensions(extension.GFM)).Parser().Parse(text.NewReader(source))
+	ast.Walk(document, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		switch node := node.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.CodeSpan, *ast.HTMLBlock, *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			if entering {
+				prose.Write(node.Segment.Value(source))
+				if node.SoftLineBreak() || node.HardLineBreak() {
+					prose.WriteString(" ")
+				}
+			}
+		default:
+			// words in different blocks or table cells are apart
+			if node.Type() == ast.TypeBlock || node.Kind() == east.KindTableCell {
+				prose.WriteString(" ")
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	count := 0
+	for _, word := range strings.Fields(prose.String()) {
+		if strings.IndexFunc(word, isWordRune) != -1 {
+			count++
+		}
+	}
+	return count
+}
+
+func isWordRune(r rune) bool {
+	return r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 127
+}
+
+// getReadingTime estimated minutes to read wordCount words
+func getReadingTime(wordCount int, wordsPerMinute int) int {
+	if wordCount == 0 {
+		return 0
+	}
+	return int(math.Ceil(float64(wordCount) / float64(wordsPerMinute)))
+}
+
+func getReadingWordsPerMinute() int {
+	wordsPerMinute := getConfig().Body.ReadingWordsPerMinute
+	if wordsPerMinute <= 0 {
+		return defaultReadingWordsPerMinute
+	}
+	return wordsPerMinute
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/github"
+)
+
+func TestGetHeadingOutline(t *testing.T) {
+	markdown := "## Usage\n\n```sh\n# not a heading\n```\n\n### Deploy `prod` [env](#env)\n\n## Usage\n\n" +
+		"Setext Title\n===\n\nSetext *Section*\n---\n\n## Ship it {{< emoji \"rocket\" >}}\n"
+	result := getHeadingOutline(markdown)
+	want := []TOCEntry{
+		{Level: 2, Title: "Usage", Anchor: "usage"},
+		{Level: 3, Title: "Deploy prod env", Anchor: "deploy-prod-env"},
+		{Level: 2, Title: "Usage", Anchor: "usage-1"},
+		{Level: 1, Title: "Setext Title", Anchor: "setext-title"},
+		{Level: 2, Title: "Setext Section", Anchor: "setext-section"},
+		{Level: 2, Title: "Ship it", Anchor: "ship-it"},
+	}
+
+	if len(result) != len(want) {
+		t.Fatalf("got %v, want %v", result, want)
+	}
+	for i := range want {
+		if result[i] != want[i] {
+			t.Errorf("got %v, want %v", result[i], want[i])
+		}
+	}
+
+	toc := getTableOfContentsMarkdown(result)
+	if !strings.Contains(toc, "    - [Deploy prod env](#deploy-prod-env)\n") {
+		t.Errorf("expected nested entry in %q", toc)
+	}
+
+	toc = getTableOfContentsMarkdown([]TOCEntry{{Level: 2, Title: "[WIP] a*b", Anchor: "wip-ab"}})
+	if !strings.Contains(toc, "- [\\[WIP\\] a\\*b](#wip-ab)\n") {
+		t.Errorf("expected the title to be escaped in %q", toc)
+	}
+}
+
+func TestCountWords(t *testing.T) {
+	markdown := "learn [serverless](https://serverless.com) on **aws**\n\n```js\nconst a = 1\n```\n\n- one two\n"
+	if result := countWords(markdown); result != 6 {
+		t.Errorf("got %d, want %d", result, 6)
+	}
+
+	markdown = "{{< alert type=\"note\" >}}\nbe careful\n{{< /alert >}}\n\n{{< mermaid >}}\ngraph TD\nA-->B\n{{< /mermaid >}}\n\nship it {{< emoji \"rocket\" >}} `go test`\n"
+	if result := countWords(markdown, "mermaid"); result != 4 {
+		t.Errorf("expected shortcodes and their code to be skipped, got %d", result)
+	}
+
+	if result := getReadingTime(401, 200); result != 3 {
+		t.Errorf("got %d, want %d", result, 3)
+	}
+}
+
+func TestGetPostFileContentsTableOfContents(t *testing.T) {
+	repo := newTestRepo("toc-playground")
+	repo.CreatedAt = &github.Timestamp{Time: time.Date(2019, 9, 10, 0, 0, 0, 0, time.UTC)}
+	repoPost := &RepoPost{
+		Repo:            repo,
+		Title:           "TOC",
+		TableOfContents: []TOCEntry{{Level: 2, Title: "Say \"hi\"", Anchor: "say-hi"}},
+		WordCount:       12,
+		ReadingTime:     1,
+	}
+
+	result, err := getPostFileContents(repoPost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"wordCount = 12\n", "readingTime = 1\n", "[[toc]]\nlevel = 2\ntitle = \"Say \\\"hi\\\"\"\nanchor = \"say-hi\"\n"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in %q", want, result)
+		}
+	}
+}
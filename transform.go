@@ -0,0 +1,383 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+const htmlShortcode = "html"
+const noneShortcode = "none"
+
+// GFMTransformOptions which GitHub-flavored constructs to rewrite and the shortcode used for each.
+// A shortcode of "html" renders plain HTML instead and "none" leaves the construct as is.
+type GFMTransformOptions struct {
+	Transforms []string
+	Shortcodes map[string]string
+}
+
+var defaultGFMShortcodes = map[string]string{
+	"alert":    "alert",
+	"emoji":    "emoji",
+	"tasklist": htmlShortcode,
+	"details":  "details",
+	"mermaid":  "mermaid",
+}
+
+// emojiCodes the emoji names transformed. names that aren't here, like :vector: in std::vector::size, are left alone
+var emojiCodes = map[string]string{
+	"+1":                       "\U0001F44D",
+	"-1":                       "\U0001F44E",
+	"100":                      "\U0001F4AF",
+	"art":                      "\U0001F3A8",
+	"bangbang":                 "‼️",
+	"beer":                     "\U0001F37A",
+	"bell":                     "\U0001F514",
+	"blue_heart":               "\U0001F499",
+	"bookmark":                 "\U0001F516",
+	"books":                    "\U0001F4DA",
+	"boom":                     "\U0001F4A5",
+	"bug":                      "\U0001F41B",
+	"bulb":                     "\U0001F4A1",
+	"calendar":                 "\U0001F4C6",
+	"chart_with_upwards_trend": "\U0001F4C8",
+	"clap":                     "\U0001F44F",
+	"clipboard":                "\U0001F4CB",
+	"cloud":                    "☁️",
+	"coffee":                   "☕",
+	"computer":                 "\U0001F4BB",
+	"confused":                 "\U0001F615",
+	"construction":             "\U0001F6A7",
+	"cry":                      "\U0001F622",
+	"dart":                     "\U0001F3AF",
+	"eyes":                     "\U0001F440",
+	"fire":                     "\U0001F525",
+	"gear":                     "⚙️",
+	"globe_with_meridians":     "\U0001F310",
+	"grin":                     "\U0001F601",
+	"hammer":                   "\U0001F528",
+	"hammer_and_wrench":        "\U0001F6E0️",
+	"heart":                    "❤️",
+	"heavy_check_mark":         "✔️",
+	"heavy_minus_sign":         "➖",
+	"heavy_plus_sign":          "➕",
+	"hourglass":                "⌛",
+	"information_source":       "ℹ️",
+	"joy":                      "\U0001F602",
+	"key":                      "\U0001F511",
+	"laughing":                 "\U0001F606",
+	"link":                     "\U0001F517",
+	"lock":                     "\U0001F512",
+	"mag":                      "\U0001F50D",
+	"memo":                     "\U0001F4DD",
+	"muscle":                   "\U0001F4AA",
+	"no_entry":                 "⛔",
+	"ok_hand":                  "\U0001F44C",
+	"package":                  "\U0001F4E6",
+	"pencil":                   "\U0001F4DD",
+	"pencil2":                  "✏️",
+	"point_right":              "\U0001F449",
+	"pray":                     "\U0001F64F",
+	"pushpin":                  "\U0001F4CC",
+	"question":                 "❓",
+	"raised_hands":             "\U0001F64C",
+	"recycle":                  "♻️",
+	"red_circle":               "\U0001F534",
+	"rocket":                   "\U0001F680",
+	"rotating_light":           "\U0001F6A8",
+	"see_no_evil":              "\U0001F648",
+	"shield":                   "\U0001F6E1️",
+	"slightly_smiling_face":    "\U0001F642",
+	"smile":                    "\U0001F604",
+	"smiley":                   "\U0001F603",
+	"sparkles":                 "✨",
+	"speech_balloon":           "\U0001F4AC",
+	"star":                     "⭐",
+	"stop_sign":                "\U0001F6D1",
+	"sunglasses":               "\U0001F60E",
+	"tada":                     "\U0001F389",
+	"thinking":                 "\U0001F914",
+	"thumbsdown":               "\U0001F44E",
+	"thumbsup":                 "\U0001F44D",
+	"trophy":                   "\U0001F3C6",
+	"truck":                    "\U0001F69A",
+	"warning":                  "⚠️",
+	"wave":                     "\U0001F44B",
+	"white_check_mark":         "✅",
+	"wink":                     "\U0001F609",
+	"wrench":                   "\U0001F527",
+	"x":                        "❌",
+	"zap":                      "⚡",
+}
+
+var alertStartRegexp = regexp.MustCompile(`^\s{0,3}>\s*\[!(NOTE|TIP|IMPORTANT|WARNING|CAUTION)\]\s*$`)
+var blockquoteLineRegexp = regexp.MustCompile(`^\s{0,3}>\s?(.*)$`)
+var emojiRegexp = regexp.MustCompile(`:([a-z0-9_+\-]+):`)
+var taskListItemRegexp = regexp.MustCompile(`(?m)^(\s*[-*+]\s+)\[([ xX])\]\s+`)
+
+func getGFMTransformOptions() GFMTransformOptions {
+	shortcodes := make(map[string]string)
+	for construct, shortcode := range defaultGFMShortcodes {
+		shortcodes[construct] = shortcode
+	}
+
+	config := getConfig()
+	for construct, shortcode := range config.Body.GFMShortcodes {
+		shortcodes[construct] = shortcode
+	}
+	options := GFMTransformOptions{
+		Transforms: config.Body.GFMTransforms,
+		Shortcodes: shortcodes,
+	}
+	if outputTarget, err := getOutputTarget(); err == nil && outputTarget.PlainHTMLShortcodes {
+		return options.plainHTML()
+	}
+	return options
+}
+
+// plainHTML the options with every shortcode rendered as plain HTML, for output that isn't rendered by hugo
+func (options GFMTransformOptions) plainHTML() GFMTransformOptions {
+	shortcodes := make(map[string]string)
+	for construct := range options.Shortcodes {
+		shortcodes[construct] = htmlShortcode
+	}
+	return GFMTransformOptions{Transforms: options.Transforms, Shortcodes: shortcodes}
+}
+
+func (options GFMTransformOptions) shortcodeFor(construct string) string {
+	for _, transform := range options.Transforms {
+		if strings.TrimSpace(transform) == construct {
+			if shortcode, ok := options.Shortcodes[construct]; ok && shortcode != "" {
+				return shortcode
+			}
+			return noneShortcode
+		}
+	}
+	return noneShortcode
+}
+
+// transformGithubFlavoredMarkdown rewrites GitHub-only markdown constructs into hugo shortcodes or plain HTML
+func transformGithubFlavoredMarkdown(markdown string, options GFMTransformOptions) string {
+	var result strings.Builder
+	for _, segment := range splitMarkdownFences(markdown) {
+		if segment.Fenced {
+			result.WriteString(transformMermaidFence(segment, options.shortcodeFor("mermaid")))
+			continue
+		}
+
+		text := transformAlerts(segment.Text, options.shortcodeFor("alert"))
+		text = transformDetails(text, options.shortcodeFor("details"))
+		text = transformTaskLists(text, options.shortcodeFor("tasklist"))
+		emojiShortcode := options.shortcodeFor("emoji")
+		text = mapMarkdownInlineText(text, func(text string) string {
+			return transformEmoji(text, emojiShortcode)
+		})
+		result.WriteString(text)
+	}
+	return result.String()
+}
+
+func transformMermaidFence(segment MarkdownSegment, shortcode string) string {
+	if shortcode == noneShortcode || getFenceLanguage(segment.Info) != "mermaid" {
+		return segment.Text
+	}
+	if shortcode == htmlShortcode {
+		return "<div class=\"mermaid\">\n" + segment.Contents + "</div>\n"
+	}
+	return fmt.Sprintf("{{< %s >}}\n%s{{< /%s >}}\n", shortcode, segment.Contents, shortcode)
+}
+
+func transformAlerts(text string, shortcode string) string {
+	if shortcode == noneShortcode {
+		return text
+	}
+
+	lines := strings.Split(text, "\n")
+	result := make([]string, 0, len(lines))
+	for i := 0; i < len(lines); i++ {
+		match := alertStartRegexp.FindStringSubmatch(lines[i])
+		if match == nil {
+			result = append(result, lines[i])
+			continue
+		}
+
+		alertType := strings.ToLower(match[1])
+		body := make([]string, 0)
+		for i+1 < len(lines) {
+			quoted := blockquoteLineRegexp.FindStringSubmatch(lines[i+1])
+			if quoted == nil {
+				break
+			}
+			body = append(body, quoted[1])
+			i++
+		}
+
+		if shortcode == htmlShortcode {
+			result = append(result, fmt.Sprintf("<div class=\"alert alert-%s\" role=\"alert\">", alertType), "")
+			result = append(result, body...)
+			result = append(result, "", "</div>")
+		} else {
+			result = append(result, fmt.Sprintf("{{< %s type=\"%s\" >}}", shortcode, alertType))
+			result = append(result, body...)
+			result = append(result, fmt.Sprintf("{{< /%s >}}", shortcode))
+		}
+	}
+	return strings.Join(result, "\n")
+}
+
+// htmlTagSpan a start or end tag and where it is in the text that was tokenized
+type htmlTagSpan struct {
+	tokenType  html.TokenType
+	name       string
+	start, end int
+}
+
+// getHTMLTagSpans the start and end tags of text in order
+func getHTMLTagSpans(text string) []htmlTagSpan {
+	spans := make([]htmlTagSpan, 0)
+	z := html.NewTokenizer(strings.NewReader(text))
+	offset := 0
+	for {
+		tokenType := z.Next()
+		if tokenType == html.ErrorToken {
+			return spans
+		}
+		start := offset
+		offset += len(z.Raw())
+		if tokenType == html.StartTagToken || tokenType == html.EndTagToken {
+			name, _ := z.TagName()
+			spans = append(spans, htmlTagSpan{tokenType: tokenType, name: string(name), start: start, end: offset})
+		}
+	}
+}
+
+// getClosingTagSpan the index of the end tag closing the start tag at spans[open], or -1 when it isn't closed
+func getClosingTagSpan(spans []htmlTagSpan, open int) int {
+	depth := 0
+	for i := open; i < len(spans); i++ {
+		if spans[i].name != spans[open].name {
+			continue
+		}
+		if spans[i].tokenType == html.StartTagToken {
+			depth++
+		} else if depth--; depth == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// transformDetails rewrites <details> blocks, nested ones included, to shortcodes. the summary is the <summary>
+// at the start of the block. a block that isn't closed is left as is
+func transformDetails(text string, shortcode string) string {
+	if shortcode == noneShortcode || shortcode == htmlShortcode {
+		return text
+	}
+
+	spans := getHTMLTagSpans(text)
+	var b strings.Builder
+	end := 0
+	for i := 0; i < len(spans); i++ {
+		if spans[i].name != "details" || spans[i].tokenType != html.StartTagToken {
+			continue
+		}
+		closing := getClosingTagSpan(spans, i)
+		if closing == -1 {
+			break
+		}
+
+		content := text[spans[i].end:spans[closing].start]
+		summary := ""
+		if inner := getHTMLTagSpans(content); len(inner) > 0 && inner[0].name == "summary" && inner[0].tokenType == html.StartTagToken &&
+			strings.TrimSpace(content[:inner[0].start]) == "" {
+			if summaryEnd := getClosingTagSpan(inner, 0); summaryEnd != -1 {
+				summary = strings.TrimSpace(content[inner[0].end:inner[summaryEnd].start])
+				content = content[inner[summaryEnd].end:]
+			}
+		}
+		if summary == "" {
+			summary = "Details"
+		}
+
+		b.WriteString(text[end:spans[i].start])
+		b.WriteString(fmt.Sprintf("{{< %s summary=%q >}}\n%s\n{{< /%s >}}", shortcode, summary, transformDetails(strings.TrimSpace(content), shortcode), shortcode))
+		end = spans[closing].end
+		i = closing
+	}
+	b.WriteString(text[end:])
+	return b.String()
+}
+
+func transformTaskLists(text string, shortcode string) string {
+	if shortcode == noneShortcode {
+		return text
+	}
+
+	return taskListItemRegexp.ReplaceAllStringFunc(text, func(item string) string {
+		match := taskListItemRegexp.FindStringSubmatch(item)
+		checked := strings.ToLower(match[2]) == "x"
+		if shortcode == htmlShortcode {
+			if checked {
+				return match[1] + "<input type=\"checkbox\" checked disabled> "
+			}
+			return match[1] + "<input type=\"checkbox\" disabled> "
+		}
+		return fmt.Sprintf("%s{{< %s checked=\"%t\" >}} ", match[1], shortcode, checked)
+	})
+}
+
+// isEmojiBoundary whether r can be next to an emoji code. letters, digits, _ and : can't, so note:x: and a::x::b stay as they are
+func isEmojiBoundary(r rune) bool {
+	return r != '_' && r != ':' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// getEmojiName the name of the known emoji code at the start of text
+func getEmojiName(text string) (string, bool) {
+	match := emojiRegexp.FindStringSubmatchIndex(text)
+	if match == nil || match[0] != 0 {
+		return "", false
+	}
+	name := text[match[2]:match[3]]
+	_, ok := emojiCodes[name]
+	return name, ok
+}
+
+// transformEmoji replaces known emoji codes that stand apart from the text around them. codes can follow each other, :tada::rocket:
+func transformEmoji(text string, shortcode string) string {
+	if shortcode == noneShortcode {
+		return text
+	}
+
+	var result strings.Builder
+	last := 0
+	for pos := 0; pos < len(text); {
+		match := emojiRegexp.FindStringIndex(text[pos:])
+		if match == nil {
+			break
+		}
+		start, end := pos+match[0], pos+match[1]
+		name, ok := getEmojiName(text[start:])
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		_, nextIsEmoji := getEmojiName(text[end:])
+		if !ok || (start > 0 && start != last && !isEmojiBoundary(before)) || (end < len(text) && !isEmojiBoundary(after) && !nextIsEmoji) {
+			// the closing colon can open the next code
+			pos = end - 1
+			continue
+		}
+
+		result.WriteString(text[last:start])
+		if shortcode == htmlShortcode {
+			result.WriteString(emojiCodes[name])
+		} else {
+			result.WriteString(fmt.Sprintf("{{< %s %q >}}", shortcode, name))
+		}
+		last, pos = end, end
+	}
+	result.WriteString(text[last:])
+	return result.String()
+}
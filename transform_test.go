@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTransformGithubFlavoredMarkdown(t *testing.T) {
+	options := GFMTransformOptions{
+		Transforms: []string{"alert", "emoji", "tasklist", "details", "mermaid"},
+		Shortcodes: defaultGFMShortcodes,
+	}
+
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{
+			name:     "alert",
+			markdown: "> [!WARNING]\n> be careful\n\nafter",
+			want:     "{{< alert type=\"warning\" >}}\nbe careful\n{{< /alert >}}\n\nafter",
+		},
+		{
+			name:     "emoji",
+			markdown: "ship it :rocket: but not `:rocket:`",
+			want:     "ship it {{< emoji \"rocket\" >}} but not `:rocket:`",
+		},
+		{
+			name:     "emoji next to each other",
+			markdown: ":tada::rocket: (:x:)",
+			want:     "{{< emoji \"tada\" >}}{{< emoji \"rocket\" >}} ({{< emoji \"x\" >}})",
+		},
+		{
+			name:     "not emoji",
+			markdown: "std::vector::size, a::x::b, note:todo:, note:x: and :not_an_emoji:",
+			want:     "std::vector::size, a::x::b, note:todo:, note:x: and :not_an_emoji:",
+		},
+		{
+			name:     "tasklist",
+			markdown: "- [x] done\n- [ ] todo\n",
+			want:     "- <input type=\"checkbox\" checked disabled> done\n- <input type=\"checkbox\" disabled> todo\n",
+		},
+		{
+			name:     "details",
+			markdown: "<details>\n<summary>Output</summary>\n\nhello\n</details>\n",
+			want:     "{{< details summary=\"Output\" >}}\nhello\n{{< /details >}}\n",
+		},
+		{
+			name:     "nested details",
+			markdown: "<details open>\n<summary class=\"title\">Outer</summary>\n\n<details><summary>Inner</summary>\n\nhello\n</details>\n\nafter\n</details>\n",
+			want:     "{{< details summary=\"Outer\" >}}\n{{< details summary=\"Inner\" >}}\nhello\n{{< /details >}}\n\nafter\n{{< /details >}}\n",
+		},
+		{
+			name:     "details not closed",
+			markdown: "<details>\n<summary>Output</summary>\n\nhello\n",
+			want:     "<details>\n<summary>Output</summary>\n\nhello\n",
+		},
+		{
+			name:     "mermaid",
+			markdown: "```mermaid\ngraph TD;\n```\n",
+			want:     "{{< mermaid >}}\ngraph TD;\n{{< /mermaid >}}\n",
+		},
+		{
+			name:     "code fence untouched",
+			markdown: "```sh\n# :rocket:\n- [ ] not a task\n```\n",
+			want:     "```sh\n# :rocket:\n- [ ] not a task\n```\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := transformGithubFlavoredMarkdown(test.markdown, options)
+			if result != test.want {
+				t.Errorf("got %q, want %q", result, test.want)
+			}
+		})
+	}
+}
+
+func TestTransformGithubFlavoredMarkdownMappings(t *testing.T) {
+	options := GFMTransformOptions{
+		Transforms: []string{"alert", "emoji", "mermaid"},
+		Shortcodes: map[string]string{"alert": "notice", "emoji": htmlShortcode, "mermaid": htmlShortcode},
+	}
+
+	result := transformGithubFlavoredMarkdown("> [!NOTE]\n> hi :tada:\n\n```mermaid\nA-->B\n```\n", options)
+	for _, want := range []string{"{{< notice type=\"note\" >}}", "hi \U0001F389", "<div class=\"mermaid\">\nA-->B\n</div>"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in %q", want, result)
+		}
+	}
+
+	if result := transformGithubFlavoredMarkdown("- [ ] todo", options); result != "- [ ] todo" {
+		t.Errorf("disabled transform changed markdown. got %q", result)
+	}
+}